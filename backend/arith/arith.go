@@ -0,0 +1,106 @@
+// Package arith implements integer arithmetic, failing on overflows instead of
+// wrapping around
+package arith
+
+import (
+	"calculator/frontend/parse/ast"
+	"errors"
+	"math"
+)
+
+var (
+	ErrOverflow       = errors.New("integer overflow")
+	ErrDivisionByZero = errors.New("division by zero")
+)
+
+// Add adds the integers
+func Add(a, b ast.Integer) (ast.Node, error) {
+	sum := a + b
+	// overflow happens only if both operands have the same sign, differing from the sum
+	if (a >= 0) == (b >= 0) && (sum >= 0) != (a >= 0) {
+		return nil, ErrOverflow
+	}
+
+	return sum, nil
+}
+
+// Sub subtracts b from a
+func Sub(a, b ast.Integer) (ast.Node, error) {
+	diff := a - b
+	if (a >= 0) != (b >= 0) && (diff >= 0) != (a >= 0) {
+		return nil, ErrOverflow
+	}
+
+	return diff, nil
+}
+
+// Mul multiplies the integers
+func Mul(a, b ast.Integer) (ast.Node, error) {
+	if a == 0 || b == 0 {
+		return ast.Integer(0), nil
+	}
+
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return nil, ErrOverflow
+	}
+
+	return product, nil
+}
+
+// Div divides a by b, truncating the result
+func Div(a, b ast.Integer) (ast.Node, error) {
+	switch {
+	case b == 0:
+		return nil, ErrDivisionByZero
+	case a == math.MinInt64 && b == -1:
+		return nil, ErrOverflow
+	}
+
+	return a / b, nil
+}
+
+// Pow raises the integer to the power by squaring, so the result is exact.
+// Negative powers result in floats, as they are fractions
+func Pow(base, exp ast.Integer) (ast.Node, error) {
+	if exp < 0 {
+		if base == 0 {
+			return nil, ErrDivisionByZero
+		}
+
+		return math.Pow(float64(base), float64(exp)), nil
+	}
+
+	result := ast.Integer(1)
+	for {
+		if exp&1 == 1 {
+			product, err := Mul(result, base)
+			if err != nil {
+				return nil, err
+			}
+
+			result = product.(ast.Integer)
+		}
+
+		exp >>= 1
+		if exp == 0 {
+			return result, nil
+		}
+
+		square, err := Mul(base, base)
+		if err != nil {
+			return nil, err
+		}
+
+		base = square.(ast.Integer)
+	}
+}
+
+// Truth returns the result of the condition, being 1 if it holds, and 0 otherwise
+func Truth(holds bool) ast.Node {
+	if holds {
+		return ast.Integer(1)
+	}
+
+	return ast.Integer(0)
+}
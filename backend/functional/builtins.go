@@ -0,0 +1,300 @@
+// Package functional provides builtins processing lists by functions, passed as
+// arguments, and combining functions
+package functional
+
+import (
+	"calculator/backend/arith"
+	"calculator/frontend/parse/ast"
+	"fmt"
+)
+
+func Builtins() map[string]ast.Node {
+	return map[string]ast.Node{
+		"list":    ast.Function(list),
+		"len":     ast.NamedFunction{Names: []string{"xs"}, Fn: length},
+		"map":     ast.NamedFunction{Names: []string{"f", "xs"}, Fn: mapList},
+		"filter":  ast.NamedFunction{Names: []string{"f", "xs"}, Fn: filter},
+		"reduce":  ast.NamedFunction{Names: []string{"f", "xs"}, Fn: reduce},
+		"fold":    ast.NamedFunction{Names: []string{"f", "init", "xs"}, Fn: fold},
+		"any":     ast.NamedFunction{Names: []string{"f", "xs"}, Fn: quantifier("any", true)},
+		"all":     ast.NamedFunction{Names: []string{"f", "xs"}, Fn: quantifier("all", false)},
+		"apply":   ast.NamedFunction{Names: []string{"f", "args"}, Fn: apply},
+		"zip":     ast.Function(zip),
+		"compose": ast.Function(compose),
+	}
+}
+
+// list makes a list of the arguments:
+//
+//	list(1, 2, 3)
+func list(args ...ast.Node) (ast.Node, error) {
+	return append(ast.List{}, args...), nil
+}
+
+// length returns the number of elements of the list:
+//
+//	len(xs)
+func length(args ...ast.Node) (ast.Node, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wanted 1 arg, got %d instead", len(args))
+	}
+
+	xs, err := toList(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Integer(len(xs)), nil
+}
+
+// mapList applies the function to every element of the list:
+//
+//	map(f, xs)
+func mapList(args ...ast.Node) (ast.Node, error) {
+	f, xs, err := callback("map", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ast.List, len(xs))
+	for i, x := range xs {
+		if result[i], err = f(x); err != nil {
+			return nil, fmt.Errorf("map: at index %d: %w", i, err)
+		}
+	}
+
+	return result, nil
+}
+
+// filter keeps elements of the list, for which the function results in a
+// non-zero number:
+//
+//	filter(f, xs)
+func filter(args ...ast.Node) (ast.Node, error) {
+	f, xs, err := callback("filter", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ast.List{}
+	for i, x := range xs {
+		keep, err := test(f, x)
+		if err != nil {
+			return nil, fmt.Errorf("filter: at index %d: %w", i, err)
+		}
+
+		if keep {
+			result = append(result, x)
+		}
+	}
+
+	return result, nil
+}
+
+// reduce combines elements of the list from left to right, starting from the
+// first one:
+//
+//	reduce(f, xs)
+func reduce(args ...ast.Node) (ast.Node, error) {
+	f, xs, err := callback("reduce", args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(xs) == 0 {
+		return nil, fmt.Errorf("cannot reduce empty list")
+	}
+
+	return accumulate("reduce", f, xs[0], xs[1:], 1)
+}
+
+// fold combines elements of the list from left to right, starting from the
+// initial value:
+//
+//	fold(f, init, xs)
+func fold(args ...ast.Node) (ast.Node, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("wanted 3 args, got %d instead", len(args))
+	}
+
+	f, xs, err := callback("fold", []ast.Node{args[0], args[2]})
+	if err != nil {
+		return nil, err
+	}
+
+	return accumulate("fold", f, args[1], xs, 0)
+}
+
+// accumulate calls the function with the accumulated value and every element
+// in turn. Offset is the index of the first element in the original list
+func accumulate(name string, f ast.Function, acc ast.Node, xs ast.List, offset int) (ast.Node, error) {
+	for i, x := range xs {
+		var err error
+		if acc, err = f(acc, x); err != nil {
+			return nil, fmt.Errorf("%s: at index %d: %w", name, i+offset, err)
+		}
+	}
+
+	return acc, nil
+}
+
+// quantifier returns the builtin checking whether the function results in a
+// non-zero number for any or all elements of the list, depending on the result
+// stopping the check:
+//
+//	any(f, xs)
+//	all(f, xs)
+func quantifier(name string, stopOn bool) ast.Function {
+	return func(args ...ast.Node) (ast.Node, error) {
+		f, xs, err := callback(name, args)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, x := range xs {
+			holds, err := test(f, x)
+			if err != nil {
+				return nil, fmt.Errorf("%s: at index %d: %w", name, i, err)
+			}
+
+			if holds == stopOn {
+				return arith.Truth(stopOn), nil
+			}
+		}
+
+		return arith.Truth(!stopOn), nil
+	}
+}
+
+// apply calls the function with elements of the list as arguments:
+//
+//	apply(f, args)
+func apply(args ...ast.Node) (ast.Node, error) {
+	f, xs, err := callback("apply", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f(xs...)
+	if err != nil {
+		return nil, fmt.Errorf("apply: %w", err)
+	}
+
+	return result, nil
+}
+
+// zip pairs up elements of the lists, making a list of lists as long as the
+// shortest one:
+//
+//	zip(xs, ys, ...)
+func zip(args ...ast.Node) (ast.Node, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("wanted at least 1 arg, got 0 instead")
+	}
+
+	lists := make([]ast.List, len(args))
+	size := -1
+	for i, arg := range args {
+		xs, err := toList(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		lists[i] = xs
+		if size == -1 || len(xs) < size {
+			size = len(xs)
+		}
+	}
+
+	result := make(ast.List, size)
+	for i := range result {
+		tuple := make(ast.List, len(lists))
+		for j, xs := range lists {
+			tuple[j] = xs[i]
+		}
+
+		result[i] = tuple
+	}
+
+	return result, nil
+}
+
+// compose returns the function applying the functions from right to left. The
+// last one takes arguments of the call, and each other the result of the next:
+//
+//	compose(f, g, ...)
+func compose(args ...ast.Node) (ast.Node, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("wanted at least 1 arg, got 0 instead")
+	}
+
+	fns := make([]ast.Function, len(args))
+	for i, arg := range args {
+		fn, ok := ast.Callee(arg)
+		if !ok {
+			return nil, fmt.Errorf("compose: %v is not a function", arg)
+		}
+
+		fns[i] = fn
+	}
+
+	return ast.Function(func(args ...ast.Node) (ast.Node, error) {
+		result, err := fns[len(fns)-1](args...)
+		if err != nil {
+			return nil, fmt.Errorf("compose: at function %d: %w", len(fns)-1, err)
+		}
+
+		for i := len(fns) - 2; i >= 0; i-- {
+			if result, err = fns[i](result); err != nil {
+				return nil, fmt.Errorf("compose: at function %d: %w", i, err)
+			}
+		}
+
+		return result, nil
+	}), nil
+}
+
+// callback takes the function and the list, being arguments of the builtin
+func callback(name string, args []ast.Node) (ast.Function, ast.List, error) {
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("wanted 2 args, got %d instead", len(args))
+	}
+
+	f, ok := ast.Callee(args[0])
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %v is not a function", name, args[0])
+	}
+
+	xs, err := toList(args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, xs, nil
+}
+
+func toList(node ast.Node) (ast.List, error) {
+	xs, ok := node.(ast.List)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as list", node)
+	}
+
+	return xs, nil
+}
+
+// test calls the predicate, which must result in a number
+func test(f ast.Function, x ast.Node) (bool, error) {
+	result, err := f(x)
+	if err != nil {
+		return false, err
+	}
+
+	switch value := result.(type) {
+	case ast.Integer:
+		return value != 0, nil
+	case ast.Float:
+		return value != 0, nil
+	}
+
+	return false, fmt.Errorf("cannot use %v as condition", result)
+}
@@ -0,0 +1,73 @@
+package functional
+
+import (
+	"calculator/frontend/parse/ast"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltins(t *testing.T) {
+	sq := ast.Function(func(args ...ast.Node) (ast.Node, error) {
+		return args[0].(ast.Integer) * args[0].(ast.Integer), nil
+	})
+	add := ast.Function(func(args ...ast.Node) (ast.Node, error) {
+		if len(args) != 2 {
+			return nil, errors.New("wanted 2 args")
+		}
+
+		return args[0].(ast.Integer) + args[1].(ast.Integer), nil
+	})
+	odd := ast.NamedFunction{Names: []string{"x"}, Fn: func(args ...ast.Node) (ast.Node, error) {
+		return args[0].(ast.Integer) % 2, nil
+	}}
+	xs := ast.List{ast.Integer(1), ast.Integer(2), ast.Integer(3)}
+
+	for _, tc := range []struct {
+		Name string
+		Fn   ast.Function
+		Args []ast.Node
+		Want ast.Node
+	}{
+		{"list", list, []ast.Node{ast.Integer(1), ast.Float(2.5)}, ast.List{ast.Integer(1), ast.Float(2.5)}},
+		{"len", length, []ast.Node{xs}, ast.Integer(3)},
+		{"map", mapList, []ast.Node{sq, xs}, ast.List{ast.Integer(1), ast.Integer(4), ast.Integer(9)}},
+		{"filter", filter, []ast.Node{odd, xs}, ast.List{ast.Integer(1), ast.Integer(3)}},
+		{"reduce", reduce, []ast.Node{add, xs}, ast.Integer(6)},
+		{"fold", fold, []ast.Node{add, ast.Integer(10), xs}, ast.Integer(16)},
+		{"any", quantifier("any", true), []ast.Node{odd, xs}, ast.Integer(1)},
+		{"all", quantifier("all", false), []ast.Node{odd, xs}, ast.Integer(0)},
+		{"all of empty", quantifier("all", false), []ast.Node{odd, ast.List{}}, ast.Integer(1)},
+		{"apply", apply, []ast.Node{add, ast.List{ast.Integer(2), ast.Integer(3)}}, ast.Integer(5)},
+		{"zip", zip, []ast.Node{xs, ast.List{ast.Integer(4)}}, ast.List{ast.List{ast.Integer(1), ast.Integer(4)}}},
+	} {
+		result, err := tc.Fn(tc.Args...)
+		require.NoError(t, err, tc.Name)
+		require.Equal(t, tc.Want, result, tc.Name)
+	}
+
+	composed, err := compose(sq, add)
+	require.NoError(t, err)
+	result, err := composed.(ast.Function)(ast.Integer(1), ast.Integer(2))
+	require.NoError(t, err)
+	require.Equal(t, ast.Integer(9), result)
+
+	for _, tc := range []struct {
+		Name string
+		Fn   ast.Function
+		Args []ast.Node
+		Err  string
+	}{
+		{"not a function", quantifier("any", true), []ast.Node{ast.Integer(1), xs}, "any: 1 is not a function"},
+		{"not a list", mapList, []ast.Node{sq, ast.Integer(1)}, "cannot use 1 as list"},
+		{"failed callback", mapList, []ast.Node{add, xs}, "map: at index 0: wanted 2 args"},
+		{"not a condition", filter, []ast.Node{ast.Function(list), xs}, "filter: at index 0: cannot use [1] as condition"},
+		{"empty reduce", reduce, []ast.Node{add, ast.List{}}, "cannot reduce empty list"},
+		{"compose non-function", compose, []ast.Node{sq, ast.Integer(2)}, "compose: 2 is not a function"},
+		{"zip nothing", zip, nil, "wanted at least 1 arg, got 0 instead"},
+	} {
+		_, err := tc.Fn(tc.Args...)
+		require.EqualError(t, err, tc.Err, tc.Name)
+	}
+}
@@ -0,0 +1,277 @@
+package interpret
+
+import (
+	"calculator/backend/arith"
+	"calculator/backend/symbolic"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse/ast"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// ErrOverflow and ErrDivisionByZero are errors of the integer arithmetic, see the
+// arith package
+var (
+	ErrOverflow       = arith.ErrOverflow
+	ErrDivisionByZero = arith.ErrDivisionByZero
+)
+
+// ErrNegativeRoot is returned on taking the square root of a negative number, as
+// complex numbers aren't supported
+var ErrNegativeRoot = errors.New("square root of negative number")
+
+func unary(budget ast.Budget, op lex.LexemeType, rawValue ast.Node) (ast.Node, error) {
+	if expr, ok := rawValue.(symbolic.Expr); ok {
+		switch op {
+		case lex.UnPlus:
+			return expr, nil
+		case lex.UnMinus:
+			return symbolic.Node(symbolic.Neg(expr)), nil
+		case lex.UnRoot:
+			root, err := symbolic.Pow(budget, expr, symbolic.Const(big.NewRat(1, 2)))
+			if err != nil {
+				return nil, err
+			}
+
+			return symbolic.Node(root), nil
+		}
+
+		return nil, fmt.Errorf("interpreter: unknown unary: %s", op)
+	}
+
+	if value, ok := rawValue.(ast.Float); ok {
+		switch op {
+		case lex.UnPlus:
+			return +value, nil
+		case lex.UnMinus:
+			return -value, nil
+		case lex.UnRoot:
+			if value < 0 {
+				return nil, fmt.Errorf("%w %v", ErrNegativeRoot, value)
+			}
+
+			return math.Sqrt(value), nil
+		}
+
+		return nil, fmt.Errorf("interpreter: unknown unary: %s", op)
+	}
+
+	value, ok := rawValue.(ast.Integer)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as integer", rawValue)
+	}
+
+	switch op {
+	case lex.UnPlus:
+		return +value, nil
+	case lex.UnMinus:
+		return arith.Sub(0, value)
+	case lex.UnRoot:
+		return sqrtInt(value)
+	}
+
+	return nil, fmt.Errorf("interpreter: unknown unary: %s", op)
+}
+
+func binary(budget ast.Budget, op lex.LexemeType, rawLeft, rawRight ast.Node) (ast.Node, error) {
+	if op.IsComparison() {
+		return compare(op, rawLeft, rawRight)
+	}
+
+	if isSymbolic(rawLeft) || isSymbolic(rawRight) {
+		return symbolicBinary(budget, op, rawLeft, rawRight)
+	}
+
+	if isFloat(rawLeft) || isFloat(rawRight) {
+		return floatBinary(op, rawLeft, rawRight)
+	}
+
+	left, ok := rawLeft.(ast.Integer)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as integer", rawLeft)
+	}
+
+	right, ok := rawRight.(ast.Integer)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as integer", rawRight)
+	}
+
+	switch op {
+	case lex.OpPlus:
+		return arith.Add(left, right)
+	case lex.OpMinus:
+		return arith.Sub(left, right)
+	case lex.OpStar:
+		return arith.Mul(left, right)
+	case lex.OpSlash:
+		return arith.Div(left, right)
+	case lex.OpCaret:
+		return arith.Pow(left, right)
+	}
+
+	return nil, fmt.Errorf("interpreter: unknown operator: %s", op)
+}
+
+// sqrtInt returns the root of a perfect square as an integer, and a float otherwise
+func sqrtInt(value ast.Integer) (ast.Node, error) {
+	if value < 0 {
+		return nil, fmt.Errorf("%w %d", ErrNegativeRoot, value)
+	}
+
+	root := math.Sqrt(float64(value))
+	if n := ast.Integer(root); root == math.Trunc(root) && n*n == value {
+		return n, nil
+	}
+
+	return root, nil
+}
+
+// compare compares the numbers, resulting in 1 if the comparison holds, and 0
+// otherwise. Integers are compared exactly, and converted to floats only when
+// compared with them
+func compare(op lex.LexemeType, rawLeft, rawRight ast.Node) (ast.Node, error) {
+	var cmp int
+	left, leftInt := rawLeft.(ast.Integer)
+	right, rightInt := rawRight.(ast.Integer)
+	if leftInt && rightInt {
+		cmp = order(left, right)
+	} else {
+		left, err := toFloat(rawLeft)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compare %v", rawLeft)
+		}
+
+		right, err := toFloat(rawRight)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compare %v", rawRight)
+		}
+
+		if math.IsNaN(left) || math.IsNaN(right) {
+			return arith.Truth(op == lex.OpNotEquals), nil
+		}
+
+		cmp = order(left, right)
+	}
+
+	switch op {
+	case lex.OpLess:
+		return arith.Truth(cmp < 0), nil
+	case lex.OpGreater:
+		return arith.Truth(cmp > 0), nil
+	case lex.OpLessEqual:
+		return arith.Truth(cmp <= 0), nil
+	case lex.OpGreaterEqual:
+		return arith.Truth(cmp >= 0), nil
+	case lex.OpEquals:
+		return arith.Truth(cmp == 0), nil
+	case lex.OpNotEquals:
+		return arith.Truth(cmp != 0), nil
+	}
+
+	return nil, fmt.Errorf("interpreter: unknown operator: %s", op)
+}
+
+func order[T ast.Integer | ast.Float](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// isTrue reports whether the condition holds, being a non-zero number
+func isTrue(node ast.Node) (bool, error) {
+	value, err := toFloat(node)
+	if err != nil {
+		return false, fmt.Errorf("cannot use %v as condition", node)
+	}
+
+	return value != 0, nil
+}
+
+func floatBinary(op lex.LexemeType, rawLeft, rawRight ast.Node) (ast.Node, error) {
+	left, err := toFloat(rawLeft)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := toFloat(rawRight)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case lex.OpPlus:
+		return left + right, nil
+	case lex.OpMinus:
+		return left - right, nil
+	case lex.OpStar:
+		return left * right, nil
+	case lex.OpSlash:
+		return left / right, nil
+	case lex.OpCaret:
+		return math.Pow(left, right), nil
+	}
+
+	return nil, fmt.Errorf("interpreter: unknown operator: %s", op)
+}
+
+func symbolicBinary(budget ast.Budget, op lex.LexemeType, rawLeft, rawRight ast.Node) (ast.Node, error) {
+	left, err := symbolic.FromNode(rawLeft)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := symbolic.FromNode(rawRight)
+	if err != nil {
+		return nil, err
+	}
+
+	var result symbolic.Expr
+	switch op {
+	case lex.OpPlus:
+		result = symbolic.Add(left, right)
+	case lex.OpMinus:
+		result = symbolic.Sub(left, right)
+	case lex.OpStar:
+		result = symbolic.Mul(left, right)
+	case lex.OpSlash:
+		result, err = symbolic.Div(left, right)
+	case lex.OpCaret:
+		result, err = symbolic.Pow(budget, left, right)
+	default:
+		return nil, fmt.Errorf("interpreter: unknown operator: %s", op)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return symbolic.Node(result), nil
+}
+
+func isSymbolic(node ast.Node) bool {
+	_, ok := node.(symbolic.Expr)
+	return ok
+}
+
+func isFloat(node ast.Node) bool {
+	_, ok := node.(ast.Float)
+	return ok
+}
+
+func toFloat(node ast.Node) (ast.Float, error) {
+	switch value := node.(type) {
+	case ast.Float:
+		return value, nil
+	case ast.Integer:
+		return ast.Float(value), nil
+	}
+
+	return 0, fmt.Errorf("cannot use %v as number", node)
+}
@@ -1,150 +1,775 @@
 package interpret
 
 import (
+	"calculator/backend/arith"
+	"calculator/backend/symbolic"
 	"calculator/frontend/lex"
 	"calculator/frontend/parse/ast"
 	"calculator/internal/chainedmap"
+	"context"
 	"fmt"
 	"math"
 	"reflect"
+	"strings"
 )
 
 type Interpreter struct {
 	names *chainedmap.ChainedMap[string, ast.Node]
+	// definitions are top-level definitions made by the user, in order
+	definitions []ast.Node
+	symbolic    bool
+	limits      Limits
+	run         *run
+	// generation changes whenever results cached by memoized functions might
+	// become stale
+	generation int
+	// memos are the memoized functions defined at the top level
+	memos map[string]*memoized
 }
 
-func NewInterpreter(names map[string]ast.Node) Interpreter {
-	return Interpreter{
-		names: chainedmap.New[string, ast.Node](names),
+// levels of the names scopes, holding builtins and the user definitions
+const (
+	builtinsLevel = iota
+	userLevel
+)
+
+// constant wraps values of names, defined as constants
+type constant struct {
+	value ast.Node
+}
+
+func NewInterpreter(names map[string]ast.Node) *Interpreter {
+	i := &Interpreter{
+		limits: DefaultLimits,
+		memos:  make(map[string]*memoized),
+	}
+
+	builtins := make(map[string]ast.Node, len(names))
+	for name, value := range names {
+		builtins[name] = i.builtin(name, value)
+	}
+
+	i.names = chainedmap.New[string, ast.Node](builtins)
+	// user definitions are kept apart from the builtins, so they can be reset
+	i.names.Push()
+
+	return i
+}
+
+// builtin prepares the builtin to be bound to the name. Limited builtins get the
+// budget of the interpreter, and the named ones get their name, if it's missing
+func (i *Interpreter) builtin(name string, value ast.Node) ast.Node {
+	switch fn := i.withBudget(value).(type) {
+	case ast.NamedFunction:
+		if len(fn.Name) == 0 {
+			fn.Name = name
+		}
+
+		return fn
+	case ast.Impure:
+		if len(fn.Name) == 0 {
+			fn.Name = name
+		}
+
+		return fn
+	default:
+		return fn
 	}
 }
 
-func (i Interpreter) Evaluate(node ast.Node) (ast.Node, error) {
+// Names returns all the names visible at the top level, both builtin and
+// defined by the user, along with their values
+func (i *Interpreter) Names() map[string]ast.Node {
+	names := i.names.Items()
+	for name, value := range names {
+		if c, ok := value.(constant); ok {
+			names[name] = c.value
+		}
+	}
+
+	return names
+}
+
+// Reset removes all the definitions made by the user
+func (i *Interpreter) Reset() {
+	i.names.Pop()
+	i.names.Push()
+	i.definitions = nil
+	i.generation++
+	i.memos = make(map[string]*memoized)
+}
+
+// Definitions returns statements being definitions, evaluated successfully, and
+// assignments to the names defined at the top level, in the order they were
+// made. Running them again restores the session. It fails if a function call
+// assigned a value, which can't be written as source, like a list
+func (i *Interpreter) Definitions() ([]ast.Node, error) {
+	for _, definition := range i.definitions {
+		if u, ok := definition.(unsaved); ok {
+			return nil, fmt.Errorf("cannot save assignment to %s: %v can't be written as source", u.name, u.value)
+		}
+	}
+
+	return i.definitions, nil
+}
+
+// Lookup returns the value of the name visible at the top level
+func (i *Interpreter) Lookup(name string) (ast.Node, bool) {
+	return i.lookup(name)
+}
+
+// Define binds the name at the top level, along with the user definitions. Unlike
+// them, it isn't recorded in Definitions
+func (i *Interpreter) Define(name string, value ast.Node) {
+	i.names.Insert(name, value)
+	i.generation++
+}
+
+// SetSymbolic toggles the symbolic mode. In this mode, names which aren't bound
+// evaluate to symbols instead of failing
+func (i *Interpreter) SetSymbolic(enabled bool) {
+	i.symbolic = enabled
+}
+
+// Evaluate evaluates the statement
+func (i *Interpreter) Evaluate(node ast.Node) (ast.Node, error) {
+	return i.EvaluateContext(context.Background(), node)
+}
+
+// EvaluateContext evaluates the statement within the limits. The evaluation is
+// aborted once the context is done
+func (i *Interpreter) EvaluateContext(ctx context.Context, node ast.Node) (ast.Node, error) {
+	defer i.Begin(ctx)()
+
+	result, err := i.evaluate(node)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.(type) {
+	case ast.Def, ast.FDef:
+		i.definitions = append(i.definitions, node)
+	}
+
+	return result, nil
+}
+
+// Call calls the function within the limits, like EvaluateContext does
+func (i *Interpreter) Call(ctx context.Context, fn ast.Function, args ...ast.Node) (ast.Node, error) {
+	defer i.Begin(ctx)()
+
+	return i.checkSize(fn(args...))
+}
+
+// EvaluateScoped evaluates the statements in order, with the names bound in a
+// scope of their own, and returns the result of the last one. Definitions made
+// by the statements are dropped along with the scope
+func (i *Interpreter) EvaluateScoped(
+	ctx context.Context, program ast.Program, names map[string]ast.Node,
+) (result ast.Node, err error) {
+	defer i.Begin(ctx)()
+
+	i.names.Push()
+	defer i.names.Pop()
+
+	for name, value := range names {
+		i.names.Insert(name, value)
+	}
+
+	for _, stmt := range program {
+		if result, err = i.evaluate(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (i *Interpreter) evaluate(node ast.Node) (ast.Node, error) {
+	if err := i.step(); err != nil {
+		return nil, err
+	}
+
 	switch node.(type) {
 	case ast.Integer, ast.Float:
 		return node, nil
+	case ast.Literal:
+		return node.(ast.Literal).Value, nil
 	case ast.ID:
-		value, found := i.names.Get(node.(ast.ID))
+		value, found := i.lookup(node.(ast.ID))
 		if !found {
+			if i.symbolic {
+				return symbolic.Var(node.(ast.ID)), nil
+			}
+
 			return nil, fmt.Errorf("name not found: %v", node)
 		}
 
 		return value, nil
 	case ast.UnOp:
 		unOp := node.(ast.UnOp)
-		rawValue, err := i.Evaluate(unOp.Value)
+		value, err := i.evaluate(unOp.Value)
 		if err != nil {
 			return nil, err
 		}
 
-		value, ok := rawValue.(ast.Integer)
-		if !ok {
-			return nil, fmt.Errorf("cannot use %v as integer", rawValue)
+		return i.checkSize(unary(budget{i}, unOp.Op, value))
+	case ast.BinOp:
+		binOp := node.(ast.BinOp)
+		left, err := i.evaluate(binOp.Left)
+		if err != nil {
+			return nil, err
 		}
 
-		switch unOp.Op {
-		case lex.UnPlus:
-			return +value, nil
-		case lex.UnMinus:
-			return -value, nil
+		right, err := i.evaluate(binOp.Right)
+		if err != nil {
+			return nil, err
 		}
 
-		return nil, fmt.Errorf("interpreter: unknown unary: %s", unOp.Op)
-	case ast.BinOp:
-		binOp := node.(ast.BinOp)
-		rawLeft, err := i.Evaluate(binOp.Left)
+		return i.checkSize(binary(budget{i}, binOp.Op, left, right))
+	case ast.FCall:
+		fcall := node.(ast.FCall)
+		target, err := i.evaluate(fcall.Target)
 		if err != nil {
 			return nil, err
 		}
-		left, ok := rawLeft.(ast.Integer)
+
+		if form, ok := target.(ast.Form); ok {
+			return form(env{i}, formArgs(fcall.Args)...)
+		}
+
+		fun, ok := ast.Callee(target)
 		if !ok {
-			return nil, fmt.Errorf("cannot use %v as integer", rawLeft)
+			return nil, fmt.Errorf("cannot call %s", reflect.TypeOf(target))
 		}
 
-		rawRight, err := i.Evaluate(binOp.Right)
+		args, named, err := i.evaluateArgs(fcall.Args)
 		if err != nil {
 			return nil, err
 		}
-		right, ok := rawRight.(ast.Integer)
-		if !ok {
-			return nil, fmt.Errorf("cannot use %v as integer", rawRight)
+
+		if len(named) > 0 {
+			if args, err = placeNamed(target, args, named); err != nil {
+				return nil, err
+			}
 		}
 
-		switch binOp.Op {
-		case lex.OpPlus:
-			return left + right, nil
-		case lex.OpMinus:
-			return left - right, nil
-		case lex.OpStar:
-			return left * right, nil
-		case lex.OpSlash:
-			return left / right, nil
-		case lex.OpCaret:
-			return ast.Integer(math.Pow(float64(left), float64(right))), nil
+		return i.checkSize(fun(args...))
+	case ast.FDef:
+		fdef := node.(ast.FDef)
+		clauses := []ast.FDef{fdef}
+		// clauses defined in the same scope are collected, until one of them
+		// matches any arguments
+		if i.names.Level(fdef.Name) == i.names.Depth() {
+			previous, _ := i.names.Get(fdef.Name)
+			if closure, ok := previous.(ast.Closure); ok && !closure.Clauses[len(closure.Clauses)-1].Total() {
+				clauses = append(append([]ast.FDef{}, closure.Clauses...), fdef)
+			}
 		}
 
-		return nil, fmt.Errorf("interpreter: unknown operator: %s", binOp.Op)
-	case ast.FCall:
-		fcall := node.(ast.FCall)
-		target, err := i.Evaluate(fcall.Target)
-		if err != nil {
+		if _, _, err := argNames(clauses); err != nil {
 			return nil, err
 		}
 
-		fun, ok := target.(ast.Function)
-		if !ok {
-			return nil, fmt.Errorf("cannot call %s", reflect.TypeOf(target))
+		fn := i.function(clauses)
+		var memo *memoized
+		if isMemo(clauses) {
+			deps, err := i.checkPure(fdef.Name, clauses, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			memo = &memoized{deps: deps}
+			fn = i.memoize(fn, memo)
 		}
 
-		var args []ast.Node
-		for _, arg := range fcall.Args {
-			evaluated, err := i.Evaluate(arg)
-			if err != nil {
+		// memoized functions may call the function being redefined, even if it's
+		// defined locally
+		i.generation++
+		closure := ast.Closure{Def: clauses[0], Clauses: clauses, Fn: fn}
+		if err := i.bind(fdef.Name, closure); err != nil {
+			return nil, err
+		}
+
+		if i.names.Depth() == userLevel {
+			if memo != nil {
+				i.memos[fdef.Name] = memo
+			} else {
+				delete(i.memos, fdef.Name)
+			}
+		}
+
+		return closure, nil
+	case ast.Def:
+		def := node.(ast.Def)
+		res, err := i.evaluate(def.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if def.Const {
+			if err = i.bind(def.Name, constant{res}); err != nil {
 				return nil, err
 			}
 
-			args = append(args, evaluated)
+			return res, nil
 		}
 
-		res, err := fun(args...)
+		return res, i.bind(def.Name, res)
+	case ast.Assign:
+		assign := node.(ast.Assign)
+		res, err := i.evaluate(assign.Value)
 		if err != nil {
 			return nil, err
 		}
 
-		return res, nil
-	case ast.FDef:
-		fdef := node.(ast.FDef)
-		body := func(args ...ast.Node) (ast.Node, error) {
-			if len(fdef.Args) != len(args) {
-				return nil, fmt.Errorf(
-					"wanted %d args, got %d instead", len(fdef.Args), len(args),
-				)
+		if i.isConstant(assign.Name) {
+			return nil, fmt.Errorf("cannot assign to constant: %s", assign.Name)
+		}
+
+		switch i.names.Level(assign.Name) {
+		case -1:
+			return nil, fmt.Errorf("cannot assign to undefined name: %s", assign.Name)
+		case builtinsLevel:
+			return nil, fmt.Errorf("cannot assign to builtin: %s", assign.Name)
+		case userLevel:
+			if err = i.checkDependents(assign.Name, res); err != nil {
+				return nil, err
 			}
 
-			i.names.Push()
-			defer i.names.Pop()
+			i.definitions = append(i.definitions, i.record(assign, res))
+			i.generation++
+		}
 
-			for index, arg := range args {
-				i.names.Insert(fdef.Args[index], arg)
+		i.names.Update(assign.Name, res)
+
+		return res, nil
+	case ast.Equation:
+		return nil, fmt.Errorf("cannot evaluate equation outside of solve")
+	case ast.Spread:
+		return nil, fmt.Errorf("cannot spread %s outside of call arguments", ast.Format(node.(ast.Spread).Value))
+	case ast.Block:
+		i.names.Push()
+		defer i.names.Pop()
+
+		var result ast.Node
+		for _, stmt := range node.(ast.Block).Body {
+			var err error
+			if result, err = i.evaluate(stmt); err != nil {
+				return nil, err
 			}
+		}
+
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("interpreter: unknown node: %s", node)
+}
+
+// unsaved is an assignment, which can't be recorded in definitions
+type unsaved struct {
+	name  string
+	value ast.Node
+}
+
+// record returns the statement restoring the assignment of the value. The
+// assignment may be made by a function call, so the new value is recorded rather
+// than the statement, unless it's written as a number already or the value can't
+// be written as source
+func (i *Interpreter) record(assign ast.Assign, value ast.Node) ast.Node {
+	// statements made at the top level depend only on the top-level names
+	topLevel := i.names.Depth() == userLevel
+	switch {
+	case topLevel && isNumber(assign.Value):
+		return assign
+	case formattable(value):
+		return ast.Assign{Name: assign.Name, Value: value}
+	case topLevel:
+		return assign
+	}
+
+	return unsaved{name: assign.Name, value: value}
+}
+
+// isNumber reports whether the node is a number, possibly negated
+func isNumber(node ast.Node) bool {
+	switch n := node.(type) {
+	case ast.Integer, ast.Float, ast.Literal:
+		return true
+	case ast.UnOp:
+		return n.Op == lex.UnMinus && isNumber(n.Value)
+	}
+
+	return false
+}
+
+// formattable reports whether formatting the value results in the source
+// evaluating to the same value
+func formattable(value ast.Node) bool {
+	switch v := value.(type) {
+	case ast.Integer:
+		// the absolute value of the smallest integer doesn't fit
+		return v != math.MinInt64
+	case ast.Float:
+		return !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+
+	return false
+}
 
-			return i.Evaluate(fdef.Body)
+// namedValue is the evaluated value of a named argument
+type namedValue struct {
+	name  string
+	value ast.Node
+}
+
+// missing fills positions of arguments, which weren't passed while the following
+// ones were passed by name
+type missing struct{}
+
+// function returns the Go function, calling the first of the clauses matching
+// the arguments
+func (i *Interpreter) function(clauses []ast.FDef) ast.Function {
+	return func(args ...ast.Node) (ast.Node, error) {
+		if len(clauses) == 1 {
+			if err := checkArity(clauses[0], len(args)); err != nil {
+				return nil, err
+			}
 		}
 
-		i.names.Insert(fdef.Name, body)
+		// the function may be called by the embedding program, apart from evaluation
+		defer i.Begin(context.Background())()
 
-		return body, nil
-	case ast.Def:
-		def := node.(ast.Def)
-		res, err := i.Evaluate(def.Value)
+		leave, err := i.enter()
 		if err != nil {
 			return nil, err
 		}
 
-		i.names.Insert(def.Name, res)
+		defer leave()
 
-		return res, nil
+		for _, clause := range clauses {
+			if checkArity(clause, len(args)) != nil {
+				continue
+			}
+
+			result, matched, err := i.call(clause, args)
+			if err != nil || matched {
+				return result, err
+			}
+		}
+
+		formatted := make([]string, len(args))
+		for index, arg := range args {
+			formatted[index] = fmt.Sprint(arg)
+		}
+
+		return nil, fmt.Errorf("no clause matches %s(%s)", clauses[0].Name, strings.Join(formatted, ", "))
 	}
+}
 
-	return nil, fmt.Errorf("interpreter: unknown node: %s", node)
+// call evaluates the body of the clause with the arguments bound in a scope of
+// their own, unless the patterns or the guard of the clause don't match them
+func (i *Interpreter) call(clause ast.FDef, args []ast.Node) (result ast.Node, matched bool, err error) {
+	i.names.Push()
+	defer i.names.Pop()
+
+	for index, pattern := range clause.Patterns {
+		if pattern == nil {
+			continue
+		}
+
+		want, err := i.evaluate(pattern)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if equal, err := compare(lex.OpEquals, want, args[index]); err != nil || equal == arith.Truth(false) {
+			return nil, false, nil
+		}
+	}
+
+	if err = i.bindArgs(clause, args); err != nil {
+		return nil, false, err
+	}
+
+	if clause.Guard != nil {
+		holds, err := i.evaluate(clause.Guard)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if matched, err = isTrue(holds); err != nil || !matched {
+			return nil, false, err
+		}
+	}
+
+	result, err = i.evaluate(clause.Body)
+
+	return result, true, err
+}
+
+// evaluateArgs evaluates arguments of a call, expanding spread lists into separate
+// arguments. Named arguments are returned apart, in order
+func (i *Interpreter) evaluateArgs(nodes []ast.Node) (args []ast.Node, named []namedValue, err error) {
+	for _, arg := range nodes {
+		if namedArg, ok := arg.(ast.NamedArg); ok {
+			value, err := i.evaluate(namedArg.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			named = append(named, namedValue{name: namedArg.Name, value: value})
+			continue
+		}
+
+		if len(named) > 0 {
+			return nil, nil, fmt.Errorf("cannot pass %s after named arguments", ast.Format(arg))
+		}
+
+		spread, isSpread := arg.(ast.Spread)
+		if isSpread {
+			arg = spread.Value
+		}
+
+		evaluated, err := i.evaluate(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !isSpread {
+			args = append(args, evaluated)
+			continue
+		}
+
+		list, ok := evaluated.(ast.List)
+		if !ok {
+			return nil, nil, fmt.Errorf("cannot spread %s: not a list", reflect.TypeOf(evaluated))
+		}
+
+		args = append(args, list...)
+	}
+
+	return args, named, nil
+}
+
+// placeNamed puts the named arguments at positions of the parameters with these
+// names. Positions left in between are filled with missing, so user functions
+// take default values for them
+func placeNamed(target ast.Node, args []ast.Node, named []namedValue) ([]ast.Node, error) {
+	var names []string
+	rest := ""
+	switch fn := target.(type) {
+	case ast.Closure:
+		// names are checked when the clauses are defined
+		names, rest, _ = argNames(fn.Clauses)
+	case ast.NamedFunction:
+		names = fn.Names
+	default:
+		return nil, fmt.Errorf("cannot pass %s by name: the function doesn't declare names of its arguments", named[0].name)
+	}
+
+	placed := append([]ast.Node{}, args...)
+	for _, arg := range named {
+		index := indexOf(names, arg.name)
+		switch {
+		case len(rest) > 0 && arg.name == rest:
+			return nil, fmt.Errorf("cannot pass variadic argument %s by name", arg.name)
+		case index < 0:
+			return nil, fmt.Errorf("unknown argument: %s", arg.name)
+		case index < len(placed) && !isMissing(placed[index]):
+			return nil, fmt.Errorf("argument %s is passed twice", arg.name)
+		}
+
+		for len(placed) <= index {
+			placed = append(placed, missing{})
+		}
+
+		placed[index] = arg.value
+	}
+
+	if _, ok := target.(ast.NamedFunction); ok {
+		for index, arg := range placed {
+			if isMissing(arg) {
+				return nil, fmt.Errorf("missing argument: %s", names[index])
+			}
+		}
+	}
+
+	return placed, nil
+}
+
+// argNames merges names of the arguments of the clauses, as pattern arguments
+// are unnamed. The variadic one is returned apart. Clauses must name the same
+// argument the same way, so it can be passed by name
+func argNames(clauses []ast.FDef) (names []string, rest string, err error) {
+	for _, clause := range clauses {
+		args := clause.Args
+		if clause.Variadic {
+			last := args[len(args)-1]
+			if len(rest) > 0 && rest != last {
+				return nil, "", fmt.Errorf("clauses of %s name the variadic argument both %s and %s", clause.Name, rest, last)
+			}
+
+			rest, args = last, args[:len(args)-1]
+		}
+
+		for index, name := range args {
+			switch {
+			case index == len(names):
+				names = append(names, name)
+			case len(name) == 0 || name == names[index]:
+			case len(names[index]) == 0:
+				names[index] = name
+			default:
+				return nil, "", fmt.Errorf(
+					"clauses of %s name argument %d both %s and %s", clause.Name, index+1, names[index], name,
+				)
+			}
+		}
+	}
+
+	return names, rest, nil
+}
+
+func isMissing(arg ast.Node) bool {
+	_, ok := arg.(missing)
+	return ok
+}
+
+func indexOf(names []string, name string) int {
+	for index, candidate := range names {
+		if candidate == name {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// formArgs turns named arguments back into equations, as forms take arguments
+// as they are written
+func formArgs(args []ast.Node) []ast.Node {
+	converted := make([]ast.Node, len(args))
+	for index, arg := range args {
+		if namedArg, ok := arg.(ast.NamedArg); ok {
+			arg = ast.Equation{Left: namedArg.Name, Right: namedArg.Value}
+		}
+
+		converted[index] = arg
+	}
+
+	return converted
+}
+
+// checkArity checks whether the function accepts that many arguments
+func checkArity(fdef ast.FDef, args int) error {
+	min, max := fdef.Arity()
+	switch {
+	case max == -1 && args < min:
+		return fmt.Errorf("wanted at least %d args, got %d instead", min, args)
+	case max == -1 || (args >= min && args <= max):
+		return nil
+	case min == max:
+		return fmt.Errorf("wanted %d args, got %d instead", min, args)
+	}
+
+	return fmt.Errorf("wanted %d to %d args, got %d instead", min, max, args)
+}
+
+// bindArgs binds the arguments of a call to the function arguments. Missing ones
+// take default values, evaluated in order, so they may refer to the preceding
+// arguments. The variadic argument is bound to the list of the remaining ones
+func (i *Interpreter) bindArgs(fdef ast.FDef, args []ast.Node) error {
+	names := fdef.Args
+	var rest ast.List
+	if fdef.Variadic {
+		names = names[:len(names)-1]
+		rest = ast.List{}
+		if len(args) > len(names) {
+			rest = append(rest, args[len(names):]...)
+		}
+	}
+
+	for index, name := range names {
+		if fdef.Patterns != nil && fdef.Patterns[index] != nil {
+			continue
+		}
+
+		var value ast.Node
+		switch {
+		case index < len(args) && !isMissing(args[index]):
+			value = args[index]
+		case fdef.Defaults == nil || fdef.Defaults[index] == nil:
+			return fmt.Errorf("missing argument: %s", name)
+		default:
+			var err error
+			if value, err = i.evaluate(fdef.Defaults[index]); err != nil {
+				return err
+			}
+		}
+
+		if err := i.bind(name, value); err != nil {
+			return err
+		}
+	}
+
+	if fdef.Variadic {
+		return i.bind(fdef.Args[len(fdef.Args)-1], rest)
+	}
+
+	return nil
+}
+
+// lookup returns the value of the name, unwrapping constants
+func (i *Interpreter) lookup(name string) (ast.Node, bool) {
+	value, found := i.names.Get(name)
+	if c, ok := value.(constant); ok {
+		return c.value, true
+	}
+
+	return value, found
+}
+
+// bind binds the name in the innermost scope. Constants can't be redefined in
+// the scope they are defined in, but may be shadowed by inner ones, like by
+// arguments of functions
+func (i *Interpreter) bind(name string, value ast.Node) error {
+	if i.names.Level(name) == i.names.Depth() && i.isConstant(name) {
+		return fmt.Errorf("cannot redefine constant: %s", name)
+	}
+
+	if i.names.Depth() == userLevel {
+		if err := i.checkDependents(name, value); err != nil {
+			return err
+		}
+
+		i.generation++
+	}
+
+	i.names.Insert(name, value)
+
+	return nil
+}
+
+func (i *Interpreter) isConstant(name string) bool {
+	value, _ := i.names.Get(name)
+	_, ok := value.(constant)
+
+	return ok
+}
+
+// env lets forms evaluate their arguments in the scope of the interpreter
+type env struct {
+	i *Interpreter
+}
+
+func (e env) Evaluate(node ast.Node, names map[string]ast.Node) (ast.Node, error) {
+	e.i.names.Push()
+	defer e.i.names.Pop()
+
+	for name, value := range names {
+		e.i.names.Insert(name, value)
+	}
+
+	return e.i.evaluate(node)
 }
@@ -0,0 +1,528 @@
+package interpret
+
+import (
+	"calculator/backend/functional"
+	"calculator/backend/numeric"
+	"calculator/backend/symbolic"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse"
+	"calculator/frontend/parse/ast"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolic(t *testing.T) {
+	for _, tc := range []struct {
+		Expr, Want string
+	}{
+		{"x + x*2 - 3x", "0"},
+		{"(a+b)^2", "(a + b)^2"},
+		{"expand((a+b)^2)", "a^2 + 2*a*b + b^2"},
+		{"(x+1)*(x-1)", "x^2 - 1"},
+		{"factor(2x^2 + 4x)", "2*x*(x + 2)"},
+		{"simplify((a+b)^2 - a^2)", "2*a*b + b^2"},
+		{"x/2 + x/3", "5*x/6"},
+		{"x/y*y", "x"},
+		{"2^x*3", "3*2^x"},
+		{"-x/(x+1)", "-x/(x + 1)"},
+		{"factor(-3x - 6)", "-3*(x + 2)"},
+	} {
+		interpreter := NewInterpreter(symbolic.Builtins())
+		interpreter.SetSymbolic(true)
+		result := evaluate(t, interpreter, tc.Expr)
+		require.Equal(t, tc.Want, fmt.Sprint(result), tc.Expr)
+	}
+}
+
+func evaluate(t *testing.T, interpreter *Interpreter, code string) (result ast.Node) {
+	tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+	require.NoError(t, err, code)
+
+	for _, stmt := range tree {
+		result, err = interpreter.Evaluate(stmt)
+		require.NoError(t, err, code)
+	}
+
+	return result
+}
+
+func TestSolve(t *testing.T) {
+	interpreter := NewInterpreter(numeric.Builtins())
+
+	roots := evaluate(t, interpreter, "solve(x^2 - 2 = 0, x)")
+	require.Len(t, roots, 2)
+	require.InDelta(t, -math.Sqrt2, roots.(ast.List)[0], 1e-12)
+	require.InDelta(t, math.Sqrt2, roots.(ast.List)[1], 1e-12)
+
+	evaluate(t, interpreter, "f(x) -> x*x*x")
+	require.Equal(t, ast.List{ast.Float(2)}, evaluate(t, interpreter, "solve(f(y) = 8, y, 0, 10)"))
+
+	_, err := interpreter.Evaluate(ast.Equation{Left: "x", Right: ast.Integer(1)})
+	require.EqualError(t, err, "cannot evaluate equation outside of solve")
+}
+
+func TestIntegrate(t *testing.T) {
+	interpreter := NewInterpreter(numeric.Builtins())
+	evaluate(t, interpreter, "f(x) -> 3*x^2")
+
+	result := evaluate(t, interpreter, "integrate(f, 0, 2)").(ast.List)
+	require.InDelta(t, 8, result[0], 1e-12)
+	require.Equal(t, ast.Integer(14), evaluate(t, interpreter, "nsum(f, 1, 2) - 1"))
+
+	_, err := interpreter.Evaluate(ast.FCall{Target: "nsum", Args: []ast.Node{"f", ast.Integer(1), "f"}})
+	require.ErrorContains(t, err, "cannot use")
+
+	evaluate(t, interpreter, "g(k) -> 2^62")
+	_, err = interpreter.Evaluate(ast.FCall{Target: "nsum", Args: []ast.Node{"g", ast.Integer(1), ast.Integer(4)}})
+	require.ErrorIs(t, err, ErrOverflow)
+
+	evaluate(t, interpreter, "one(k) -> 1")
+	require.Equal(t, ast.Integer(2), evaluate(t, interpreter, "nsum(one, 2^62 + (2^62 - 2), 2^62 + (2^62 - 1))"))
+	require.Equal(t, ast.Integer(0), evaluate(t, interpreter, "nsum(one, 2, 1)"))
+
+	interpreter.SetLimits(Limits{MaxSteps: 1000})
+	_, err = interpreter.Evaluate(ast.FCall{Target: "nsum", Args: []ast.Node{"f", ast.Integer(1), ast.Integer(1e9)}})
+	require.ErrorIs(t, err, ErrStepLimit)
+}
+
+func TestHigherOrder(t *testing.T) {
+	interpreter := NewInterpreter(functional.Builtins())
+	evaluate(t, interpreter, "sq(x) -> x^2; add(a, b) -> a + b; odd(x) -> x - 2*(x/2) != 0; xs -> list(1, 2, 3)")
+
+	for code, want := range map[string]ast.Node{
+		"map(sq, xs)":                 ast.List{ast.Integer(1), ast.Integer(4), ast.Integer(9)},
+		"filter(odd, xs)":             ast.List{ast.Integer(1), ast.Integer(3)},
+		"reduce(add, xs)":             ast.Integer(6),
+		"fold(add, 10, xs = xs)":      ast.Integer(16),
+		"zip(xs, list(4, 5))":         ast.List{ast.List{ast.Integer(1), ast.Integer(4)}, ast.List{ast.Integer(2), ast.Integer(5)}},
+		"any(odd, xs) + all(odd, xs)": ast.Integer(1),
+		"all(odd, list())":            ast.Integer(1),
+		"apply(add, list(2, 3))":      ast.Integer(5),
+		"compose(sq, add)(1, 2)":      ast.Integer(9),
+		"map(compose(len, list), xs)": ast.List{ast.Integer(1), ast.Integer(1), ast.Integer(1)},
+	} {
+		require.Equal(t, want, evaluate(t, interpreter, code), code)
+	}
+
+	require.Equal(t, "<builtin map(f, xs)>", fmt.Sprint(evaluate(t, interpreter, "map")))
+	require.Equal(t, "<builtin (f, xs)>", fmt.Sprint(ast.NamedFunction{Names: []string{"f", "xs"}}))
+	require.Equal(t, "<builtin print(...)>", fmt.Sprint(ast.Impure{Name: "print"}))
+
+	evaluate(t, interpreter, "inv(x) -> 1 / x")
+	for code, want := range map[string]string{
+		"map(inv, list(1, 0))":              "map: at index 1: division by zero",
+		"fold(add, 1, list(2, inv))":        "fold: at index 1: cannot use inv(x) as integer",
+		"reduce(add, list())":               "cannot reduce empty list",
+		"filter(sq, 1)":                     "cannot use 1 as list",
+		"any(1, xs)":                        "any: 1 is not a function",
+		"compose(sq, 2)":                    "compose: 2 is not a function",
+		"map(compose(inv, sq), list(1, 0))": "map: at index 1: compose: at function 0: division by zero",
+		"filter(map, xs)":                   "filter: at index 0: wanted 2 args, got 1 instead",
+		"apply(add, list(1))":               "apply: wanted 2 args, got 1 instead",
+	} {
+		tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+		require.NoError(t, err, code)
+		_, err = interpreter.Evaluate(tree[0])
+		require.EqualError(t, err, want, code)
+	}
+}
+
+func TestReset(t *testing.T) {
+	interpreter := NewInterpreter(map[string]ast.Node{"x": ast.Integer(5)})
+	evaluate(t, interpreter, "x -> 1")
+	evaluate(t, interpreter, "y -> 2")
+	require.Equal(t, map[string]ast.Node{"x": ast.Integer(1), "y": ast.Integer(2)}, interpreter.Names())
+
+	interpreter.Reset()
+	require.Equal(t, map[string]ast.Node{"x": ast.Integer(5)}, interpreter.Names())
+}
+
+func TestDefinitions(t *testing.T) {
+	interpreter := NewInterpreter(map[string]ast.Node{"sum": ast.Function(nil)})
+	evaluate(t, interpreter, "a -> 2")
+	closure := evaluate(t, interpreter, "f(x, y) -> x + y*2")
+	require.Equal(t, "f(x, y)", fmt.Sprint(closure))
+	evaluate(t, interpreter, "a -> f(1, a) + 1")
+	evaluate(t, interpreter, "a + 1")
+	_, err := interpreter.Evaluate(ast.Def{Name: "b", Value: "nope"})
+	require.Error(t, err)
+
+	require.Equal(t, []string{
+		"a -> 2",
+		"f(x, y) -> x + y * 2",
+		"a -> f(1, a) + 1",
+	}, sources(t, interpreter))
+}
+
+// sources formats definitions of the interpreter
+func sources(t *testing.T, interpreter *Interpreter) []string {
+	definitions, err := interpreter.Definitions()
+	require.NoError(t, err)
+
+	formatted := make([]string, len(definitions))
+	for i, def := range definitions {
+		formatted[i] = ast.Format(def)
+	}
+
+	return formatted
+}
+
+func TestBlock(t *testing.T) {
+	interpreter := NewInterpreter(nil)
+	code := "a -> 1\nf(x) -> {\n  a -> x^2\n  b -> a + 1\n  a * b\n}\nf(3) + a"
+	require.Equal(t, ast.Integer(91), evaluate(t, interpreter, code))
+
+	_, found := interpreter.Lookup("b")
+	require.False(t, found)
+	require.Equal(t, ast.Integer(6), evaluate(t, interpreter, "{ c -> 2; { c -> c + 1; c } * c }"))
+}
+
+func TestAssign(t *testing.T) {
+	interpreter := NewInterpreter(map[string]ast.Node{"pi": ast.Float(3.14)})
+	code := "n -> 0\ninc(x) -> n := n + x\ninc(2); inc(3)\nf(n) -> { n := 10; n }\nf(1) + n"
+	require.Equal(t, ast.Integer(15), evaluate(t, interpreter, code))
+
+	require.Equal(t, []string{
+		"n -> 0", "inc(x) -> n := n + x", "n := 2", "n := 5", "f(n) -> { n := 10; n }",
+	}, sources(t, interpreter))
+
+	evaluate(t, interpreter, "const g -> 9.81")
+	for code, want := range map[string]string{
+		"m := 1":    "cannot assign to undefined name: m",
+		"pi := 3":   "cannot assign to builtin: pi",
+		"g := 1":    "cannot assign to constant: g",
+		"g -> 1":    "cannot redefine constant: g",
+		"g(x) -> x": "cannot redefine constant: g",
+	} {
+		tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+		require.NoError(t, err)
+
+		for _, stmt := range tree {
+			_, err = interpreter.Evaluate(stmt)
+		}
+
+		require.EqualError(t, err, want, code)
+	}
+
+	require.Equal(t, ast.Float(9.81), interpreter.Names()["g"])
+
+	// constants may be shadowed by inner scopes
+	require.Equal(t, ast.Integer(3), evaluate(t, interpreter, "h(g) -> g; h(3)"))
+	require.Equal(t, ast.Integer(0), evaluate(t, interpreter, "r(0) -> 0; r(n) -> { const k -> n; r(n-1) }; r(3)"))
+	require.Equal(t, ast.Integer(2), evaluate(t, interpreter, "{ g -> 2; g := g * 1; g }"))
+	require.Equal(t, ast.Float(9.81), interpreter.Names()["g"])
+}
+
+func TestMemo(t *testing.T) {
+	var said ast.List
+	interpreter := NewInterpreter(map[string]ast.Node{
+		"pi": ast.Float(3.14),
+		"say": ast.Impure{Fn: func(args ...ast.Node) (ast.Node, error) {
+			said = append(said, args...)
+			return ast.Integer(0), nil
+		}},
+	})
+	interpreter.SetLimits(Limits{MaxSteps: 10000})
+	evaluate(t, interpreter, "memo fib(n) if n < 2 -> n\nmemo fib(n) -> fib(n-1) + fib(n-2)")
+	require.Equal(t, ast.Integer(23416728348467685), evaluate(t, interpreter, "fib(80)"))
+	interpreter.ClearCache()
+	require.Equal(t, ast.Integer(23416728348467685), evaluate(t, interpreter, "fib(80)"))
+
+	evaluate(t, interpreter, "const k -> 2; calls -> 0; count(x) -> calls := calls + 1")
+	evaluate(t, interpreter, "memo area(r) -> { sq(x) -> x * x; a -> pi * sq(r); a := a * k; a }")
+	evaluate(t, interpreter, "memo twice(x) -> area(x) + area(x)")
+	require.Equal(t, ast.Float(12.56), evaluate(t, interpreter, "twice(1)"))
+	require.Equal(t, ast.Float(31.400000000000002), evaluate(t, interpreter, "scaled(k) -> area(1); scaled(10)"))
+	require.Equal(t, ast.Float(6.28), evaluate(t, interpreter, "area(1)"))
+
+	evaluate(t, interpreter, "memo g(x) -> fib(x)")
+	require.Equal(t, ast.Integer(55), evaluate(t, interpreter, "g(10)"))
+	evaluate(t, interpreter, "fib(n) -> 0")
+	require.Equal(t, ast.Integer(0), evaluate(t, interpreter, "g(10)"))
+
+	// names are resolved where the function is called, so the cache isn't used
+	// while the functions it calls are shadowed
+	evaluate(t, interpreter, "v -> 1; id(x) -> x; memo h(x) -> id(x); shadow(v) -> { id(x) -> x + v; h(1) }")
+	require.Equal(t, ast.Integer(1), evaluate(t, interpreter, "h(1)"))
+	require.Equal(t, ast.Integer(101), evaluate(t, interpreter, "shadow(100)"))
+	require.Equal(t, ast.Integer(201), evaluate(t, interpreter, "shadow(200)"))
+	require.Equal(t, ast.Integer(1), evaluate(t, interpreter, "h(1)"))
+
+	for code, want := range map[string]string{
+		"memo f(x) -> count(x)":                       "cannot memoize f: calls count, which assigns to calls",
+		"memo f(x) -> x * calls":                      "cannot memoize f: refers to variable calls",
+		"memo f(x) -> x * y":                          "cannot memoize f: refers to undefined name y",
+		"memo f(x, y -> calls) -> xy":                 "cannot memoize f: refers to variable calls",
+		"fib(n) -> calls":                             "cannot redefine fib: cannot memoize g: calls fib, which refers to variable calls",
+		"memo f(x) -> say(x)":                         "cannot memoize f: calls impure builtin say",
+		"loud(x) -> say(x) + x; memo f(x) -> loud(x)": "cannot memoize f: calls loud, which calls impure builtin say",
+		"id(x) -> x + v":                              "cannot redefine id: cannot memoize h: calls id, which refers to variable v",
+		"fib := count":                                "cannot redefine fib: cannot memoize g: calls fib, which assigns to calls",
+	} {
+		tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+		require.NoError(t, err, code)
+
+		for _, stmt := range tree {
+			_, err = interpreter.Evaluate(stmt)
+		}
+
+		require.EqualError(t, err, want, code)
+	}
+
+	require.Equal(t, ast.Integer(0), evaluate(t, interpreter, "say(1, 2)"))
+	require.Equal(t, ast.List{ast.Integer(1), ast.Integer(2)}, said)
+}
+
+func TestSaveAssignments(t *testing.T) {
+	interpreter := NewInterpreter(functional.Builtins())
+	code := "f(x) -> x * 2; g -> 1; g := f\nxs -> list(1); xs := list(1, 2)\na -> 0; a := 0x1F; b -> 0; b := -1.5\n" +
+		"set(x) -> a := x; set(-5)\ng(a) + len(xs) + b"
+	require.Equal(t, ast.Float(-9.5), evaluate(t, interpreter, code))
+	require.Subset(t, sources(t, interpreter), []string{"g := f", "xs := list(1, 2)", "a := 0x1F", "b := -1.5", "a := -5"})
+
+	// running the definitions again restores the session
+	restored := NewInterpreter(functional.Builtins())
+	evaluate(t, restored, strings.Join(sources(t, interpreter), "\n"))
+	require.Equal(t, ast.Float(-9.5), evaluate(t, restored, "g(a) + len(xs) + b"))
+
+	evaluate(t, interpreter, "clear() -> xs := list(); clear()")
+	_, err := interpreter.Definitions()
+	require.EqualError(t, err, "cannot save assignment to xs: [] can't be written as source")
+}
+
+func TestArgs(t *testing.T) {
+	interpreter := NewInterpreter(map[string]ast.Node{
+		"count": ast.Function(func(args ...ast.Node) (ast.Node, error) {
+			return ast.Integer(len(args)), nil
+		}),
+	})
+	evaluate(t, interpreter, "log(x, base -> 10, k -> base * 2) -> x + base + k")
+	evaluate(t, interpreter, "rest(a, b...) -> count(b..., a, b...)")
+	evaluate(t, interpreter, "list(xs...) -> xs")
+
+	for code, want := range map[string]ast.Node{
+		"log(1)":              ast.Integer(31),
+		"log(1, 2)":           ast.Integer(7),
+		"log(1, 2, 3)":        ast.Integer(6),
+		"rest(1)":             ast.Integer(1),
+		"rest(1, 2, 3)":       ast.Integer(5),
+		"rest(list(1, 2)...)": ast.Integer(3),
+	} {
+		require.Equal(t, want, evaluate(t, interpreter, code), code)
+	}
+
+	for code, want := range map[string]ast.Node{
+		"log(1, k = 0)":               ast.Integer(11),
+		"log(k = 0, base = 2, x = 3)": ast.Integer(5),
+	} {
+		require.Equal(t, want, evaluate(t, interpreter, code), code)
+	}
+
+	for code, want := range map[string]string{
+		"log(1, y = 2)":   "unknown argument: y",
+		"log(1, x = 2)":   "argument x is passed twice",
+		"log(k = 2)":      "missing argument: x",
+		"log(x = 1, 2)":   "cannot pass 2 after named arguments",
+		"rest(1, b = 2)":  "cannot pass variadic argument b by name",
+		"count(a = 1)":    "cannot pass a by name: the function doesn't declare names of its arguments",
+		"log()":           "wanted 1 to 3 args, got 0 instead",
+		"log(1, 2, 3, 4)": "wanted 1 to 3 args, got 4 instead",
+		"rest()":          "wanted at least 1 args, got 0 instead",
+		"rest(1...)":      "cannot spread int64: not a list",
+	} {
+		tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+		require.NoError(t, err, code)
+
+		_, err = interpreter.Evaluate(tree[0])
+		require.EqualError(t, err, want, code)
+	}
+}
+
+func TestClauses(t *testing.T) {
+	interpreter := NewInterpreter(nil)
+	evaluate(t, interpreter, "fib(0) -> 0\nfib(1) -> 1\nfib(n) -> fib(n-1) + fib(n-2)")
+	evaluate(t, interpreter, "sign(x) if x < 0 -> -1; sign(0) -> 0; sign(x) if x > 0 -> 1")
+	evaluate(t, interpreter, "f(x) -> 1; f(x) -> 2")
+	evaluate(t, interpreter, "first(0) -> 1; first(x) -> 2")
+	evaluate(t, interpreter, "pick(0, y) -> y; pick(x, y) -> x")
+
+	for code, want := range map[string]ast.Node{
+		"fib(15)":                    ast.Integer(610),
+		"sign(-5) + sign(0.0)":       ast.Integer(-1),
+		"sign(2.5)":                  ast.Integer(1),
+		"f(0)":                       ast.Integer(2),
+		"1 < 2 == (2 >= 2) != 3 > 4": ast.Integer(0),
+		"0.1 + 0.2 == 0.3":           ast.Integer(0),
+		"first(x = 0)":               ast.Integer(1),
+		"first(x = 3)":               ast.Integer(2),
+		"pick(y = 5, x = 0)":         ast.Integer(5),
+		"pick(y = 5, x = 2)":         ast.Integer(2),
+	} {
+		require.Equal(t, want, evaluate(t, interpreter, code), code)
+	}
+
+	evaluate(t, interpreter, "fib(n) -> n")
+	require.Equal(t, ast.Integer(7), evaluate(t, interpreter, "fib(7)"))
+
+	for code, want := range map[string]string{
+		"sign(0 - 0.0/0)":                         "no clause matches sign(NaN)",
+		"sign(1, 2)":                              "no clause matches sign(1, 2)",
+		"g(x) if x -> x; g(0)":                    "no clause matches g(0)",
+		"h(x) if f -> x; h(0)":                    "cannot use f(x) as condition",
+		"sign(0, 1) > 0":                          "no clause matches sign(0, 1)",
+		"q(a) if a -> a; q(0) -> 0; q(b) -> b":    "clauses of q name argument 1 both a and b",
+		"r(a, xs...) if a -> 0; r(a, ys...) -> 1": "clauses of r name the variadic argument both xs and ys",
+	} {
+		tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+		require.NoError(t, err, code)
+
+		for _, stmt := range tree {
+			_, err = interpreter.Evaluate(stmt)
+		}
+
+		require.EqualError(t, err, want, code)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	for _, tc := range []struct {
+		Name   string
+		Limits Limits
+		Code   string
+		Err    error
+	}{
+		{"depth", DefaultLimits, "f(x) -> f(x)\nf(1)", ErrDepthLimit},
+		{"steps", Limits{MaxSteps: 1000}, "f(x) -> f(x) + 1\nf(1)", ErrStepLimit},
+		{"time", Limits{Timeout: 10 * time.Millisecond}, "f(x) -> f(x)\nf(1)", ErrTimeLimit},
+		{"number", Limits{MaxNumberBits: 16}, "2^20", ErrNumberLimit},
+		{"not a number", Limits{MaxNumberBits: 16}, "0.0/0", ErrNumberLimit},
+		{"number in symbolic", Limits{MaxNumberBits: 16}, "a -> simplify(y * 2^10)\na * 2^10", ErrNumberLimit},
+		{"power in symbolic", Limits{MaxNumberBits: 4096}, "(3*y)^300000000", ErrNumberLimit},
+		{"expansion size", Limits{MaxNumberBits: 64}, "expand((y + 1)^1000)", ErrNumberLimit},
+		{"expansion steps", Limits{MaxSteps: 1000}, "expand((a + b + c)^100)", ErrStepLimit},
+	} {
+		interpreter := NewInterpreter(symbolic.Builtins())
+		interpreter.SetSymbolic(true)
+		interpreter.SetLimits(tc.Limits)
+
+		tree, err := parse.NewParser(lex.NewLexer(tc.Code)).Parse()
+		require.NoError(t, err)
+
+		for _, stmt := range tree {
+			_, err = interpreter.Evaluate(stmt)
+		}
+
+		require.ErrorIs(t, err, tc.Err, tc.Name)
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewInterpreter(nil).EvaluateContext(ctx, ast.Integer(1))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("one run", func(t *testing.T) {
+		interpreter := NewInterpreter(nil)
+		interpreter.SetLimits(Limits{MaxSteps: 100})
+		evaluate(t, interpreter, "down(0) -> 0; down(n) -> down(n - 1)")
+		evaluate(t, interpreter, "down(10)")
+		evaluate(t, interpreter, "down(10)")
+
+		defer interpreter.Begin(context.Background())()
+		evaluate(t, interpreter, "down(10)")
+
+		_, err := interpreter.Evaluate(ast.FCall{Target: "down", Args: []ast.Node{ast.Integer(10)}})
+		require.ErrorIs(t, err, ErrStepLimit)
+	})
+
+	t.Run("limited builtin", func(t *testing.T) {
+		interpreter := NewInterpreter(map[string]ast.Node{
+			"spin": ast.Limited{Fn: func(budget ast.Budget, args ...ast.Node) (ast.Node, error) {
+				for {
+					if err := budget.Step(1); err != nil {
+						return nil, err
+					}
+				}
+			}},
+			"grow": ast.Limited{Names: []string{"bits"}, Fn: func(budget ast.Budget, args ...ast.Node) (ast.Node, error) {
+				return args[0], budget.CheckBits(int(args[0].(ast.Integer)))
+			}},
+		})
+		interpreter.SetLimits(Limits{MaxSteps: 1000, MaxNumberBits: 16})
+
+		for code, want := range map[string]error{
+			"spin()":         ErrStepLimit,
+			"grow(bits = 8)": nil,
+			"grow(32)":       ErrNumberLimit,
+		} {
+			tree, err := parse.NewParser(lex.NewLexer(code)).Parse()
+			require.NoError(t, err)
+
+			_, err = interpreter.Evaluate(tree[0])
+			require.ErrorIs(t, err, want, code)
+		}
+	})
+
+	t.Run("within limits", func(t *testing.T) {
+		interpreter := NewInterpreter(nil)
+		interpreter.SetLimits(Limits{MaxDepth: 10, MaxSteps: 100, Timeout: time.Second, MaxNumberBits: 16})
+		require.Equal(t, ast.Integer(1024), evaluate(t, interpreter, "f(x) -> x*2\nf(f(2^8))"))
+
+		interpreter = NewInterpreter(symbolic.Builtins())
+		interpreter.SetSymbolic(true)
+		interpreter.SetLimits(Limits{MaxNumberBits: 16})
+		require.Equal(t, "32768*y^15", fmt.Sprint(evaluate(t, interpreter, "(2*y)^15")))
+		require.Equal(t, "y^4 + 4*y^3 + 6*y^2 + 4*y + 1", fmt.Sprint(evaluate(t, interpreter, "expand((y + 1)^4)")))
+	})
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	for _, tc := range []struct {
+		Expr string
+		Want ast.Node
+	}{
+		{"3^39", ast.Integer(4052555153018976267)},
+		{"2^62 + (2^62 - 1)", ast.Integer(math.MaxInt64)},
+		{"(-2)^63", ast.Integer(math.MinInt64)},
+		{"(-1)^1000001", ast.Integer(-1)},
+		{"2^-2", ast.Float(0.25)},
+		{"7/-2", ast.Integer(-3)},
+		{"√49", ast.Integer(7)},
+		{"√2 × √2", ast.Float(2.0000000000000004)},
+		{"0x1F + 0o17 + 0b1011", ast.Integer(57)},
+		{"1_000_000 / 1e3", ast.Float(1000)},
+	} {
+		require.Equal(t, tc.Want, evaluate(t, NewInterpreter(nil), tc.Expr), tc.Expr)
+	}
+
+	for _, tc := range []struct {
+		Expr string
+		Err  error
+	}{
+		{"1/0", ErrDivisionByZero},
+		{"0^-1", ErrDivisionByZero},
+		{"2^63", ErrOverflow},
+		{"3^40", ErrOverflow},
+		{"2^62 + 2^62", ErrOverflow},
+		{"-(2^62) - 2^62 - 1", ErrOverflow},
+		{"-((-2)^63)", ErrOverflow},
+		{"(-2)^63 / -1", ErrOverflow},
+		{"4294967296 * 4294967296", ErrOverflow},
+		{"√-4", ErrNegativeRoot},
+		{"√(0 - 2.25)", ErrNegativeRoot},
+	} {
+		tree, err := parse.NewParser(lex.NewLexer(tc.Expr)).Parse()
+		require.NoError(t, err)
+
+		_, err = NewInterpreter(nil).Evaluate(tree[0])
+		require.ErrorIs(t, err, tc.Err, tc.Expr)
+	}
+}
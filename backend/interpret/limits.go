@@ -0,0 +1,206 @@
+package interpret
+
+import (
+	"calculator/backend/symbolic"
+	"calculator/frontend/parse/ast"
+	"context"
+	"errors"
+	"math"
+	"math/bits"
+	"time"
+)
+
+var (
+	ErrDepthLimit  = errors.New("recursion depth limit exceeded")
+	ErrStepLimit   = errors.New("evaluation step limit exceeded")
+	ErrTimeLimit   = errors.New("evaluation time limit exceeded")
+	ErrNumberLimit = errors.New("number size limit exceeded")
+)
+
+// Limits bound resources a single evaluation may take. Zero means no limit
+type Limits struct {
+	// MaxDepth limits how deep calls of user functions may nest
+	MaxDepth int
+	// MaxSteps limits the number of evaluated nodes
+	MaxSteps int
+	// Timeout limits the wall-clock time
+	Timeout time.Duration
+	// MaxNumberBits limits the size of produced numbers, being the bit length of
+	// integers, of integral parts of floats and of rationals in symbolic expressions.
+	// Infinities and NaN exceed any limit
+	MaxNumberBits int
+}
+
+// DefaultLimits only prevent unbounded recursion from crashing the process
+var DefaultLimits = Limits{MaxDepth: 1000}
+
+// SetLimits replaces the limits applied to every following evaluation
+func (i *Interpreter) SetLimits(limits Limits) {
+	i.limits = limits
+}
+
+// run is the state of the running evaluation
+type run struct {
+	ctx      context.Context
+	deadline time.Time
+	steps    int
+	depth    int
+}
+
+// Begin starts a single evaluation, which is over once the returned function is
+// called. Statements evaluated until then share its limits, rather than each of
+// them getting its own, and their contexts are ignored in favor of the one passed
+// here. Evaluations started while another one is running, like by a builtin
+// calling back a function, share its state as well
+func (i *Interpreter) Begin(ctx context.Context) (end func()) {
+	if i.run != nil {
+		return func() {}
+	}
+
+	i.run = &run{ctx: ctx}
+	cancel := context.CancelFunc(func() {})
+	if i.limits.Timeout > 0 {
+		i.run.deadline = time.Now().Add(i.limits.Timeout)
+		i.run.ctx, cancel = context.WithDeadline(ctx, i.run.deadline)
+	}
+
+	return func() {
+		cancel()
+		i.run = nil
+	}
+}
+
+// step accounts a single node being evaluated
+func (i *Interpreter) step() error {
+	return i.advance(1)
+}
+
+// advance accounts the steps done, checking whether the evaluation may go on
+func (i *Interpreter) advance(steps int) error {
+	if i.run == nil {
+		return nil
+	}
+
+	i.run.steps += steps
+	if i.limits.MaxSteps > 0 && i.run.steps > i.limits.MaxSteps {
+		return ErrStepLimit
+	}
+
+	select {
+	case <-i.run.ctx.Done():
+		if !i.run.deadline.IsZero() && !time.Now().Before(i.run.deadline) {
+			return ErrTimeLimit
+		}
+
+		return i.run.ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// enter accounts a call of a user function. The returned function must be called
+// once the call is over
+func (i *Interpreter) enter() (leave func(), err error) {
+	if i.run == nil {
+		return func() {}, nil
+	}
+
+	i.run.depth++
+	leave = func() {
+		i.run.depth--
+	}
+
+	if i.limits.MaxDepth > 0 && i.run.depth > i.limits.MaxDepth {
+		leave()
+		return nil, ErrDepthLimit
+	}
+
+	return leave, nil
+}
+
+// checkSize passes the result of an operation through, failing if it is a number
+// larger than allowed
+func (i *Interpreter) checkSize(value ast.Node, err error) (ast.Node, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if err = i.checkBits(numberBits(value)); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (i *Interpreter) checkBits(size int) error {
+	if i.limits.MaxNumberBits > 0 && size > i.limits.MaxNumberBits {
+		return ErrNumberLimit
+	}
+
+	return nil
+}
+
+// budget lets builtins account their work against limits of the running
+// evaluation. Outside of evaluations, only the number size is limited
+type budget struct {
+	i *Interpreter
+}
+
+func (b budget) Step(steps int) error {
+	return b.i.advance(steps)
+}
+
+func (b budget) CheckBits(size int) error {
+	return b.i.checkBits(size)
+}
+
+// withBudget turns the limited builtin into a function, accounting its work by
+// the interpreter. Other values are returned as they are
+func (i *Interpreter) withBudget(builtin ast.Node) ast.Node {
+	limited, ok := builtin.(ast.Limited)
+	if !ok {
+		return builtin
+	}
+
+	fn := func(args ...ast.Node) (ast.Node, error) {
+		return limited.Fn(budget{i}, args...)
+	}
+	if limited.Names != nil {
+		return ast.NamedFunction{Names: limited.Names, Fn: fn}
+	}
+
+	return ast.Function(fn)
+}
+
+func numberBits(value ast.Node) int {
+	switch v := value.(type) {
+	case ast.Integer:
+		if v < 0 {
+			return bits.Len64(uint64(-v))
+		}
+
+		return bits.Len64(uint64(v))
+	case ast.Float:
+		// NaN may come from overflown values as well, like Inf - Inf
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return math.MaxInt
+		}
+
+		if _, exp := math.Frexp(v); exp > 0 {
+			return exp
+		}
+	case symbolic.Expr:
+		return v.Bits()
+	case ast.List:
+		size := 0
+		for _, elem := range v {
+			if elemBits := numberBits(elem); elemBits > size {
+				size = elemBits
+			}
+		}
+
+		return size
+	}
+
+	return 0
+}
@@ -0,0 +1,324 @@
+package interpret
+
+import (
+	"calculator/frontend/parse/ast"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// memoSize bounds the number of results cached by a single function. The cache
+// is dropped as a whole once it's full
+const memoSize = 10000
+
+// ClearCache drops the results cached by memoized functions
+func (i *Interpreter) ClearCache() {
+	i.generation++
+}
+
+// memoized is a memoized function, defined by the user
+type memoized struct {
+	// deps are names the function refers to, directly or through other
+	// functions, along with the levels of scopes they are found at
+	deps map[string]int
+}
+
+// memoize wraps the function, so its results are cached by values of the
+// arguments. Only calls with numbers as arguments are cached. The cache is
+// dropped when names visible to the function might have changed, and isn't used
+// while any of them is shadowed
+func (i *Interpreter) memoize(fn ast.Function, memo *memoized) ast.Function {
+	results := make(map[string]ast.Node)
+	generation := i.generation
+
+	return func(args ...ast.Node) (ast.Node, error) {
+		key, ok := memoKey(args)
+		if !ok || i.shadowed(memo) {
+			return fn(args...)
+		}
+
+		if generation != i.generation || len(results) >= memoSize {
+			results = make(map[string]ast.Node)
+			generation = i.generation
+		}
+
+		if result, found := results[key]; found {
+			return result, nil
+		}
+
+		result, err := fn(args...)
+		if err != nil {
+			return nil, err
+		}
+
+		// the cache could be dropped while the result was being evaluated
+		if generation == i.generation {
+			results[key] = result
+		}
+
+		return result, nil
+	}
+}
+
+// shadowed reports whether any name the function refers to is bound in another
+// scope than it was at the definition, as names are resolved where the function
+// is called
+func (i *Interpreter) shadowed(memo *memoized) bool {
+	for name, level := range memo.deps {
+		if i.names.Level(name) != level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDependents checks whether memoized functions defined at the top level
+// stay pure, if the name is bound to the value there
+func (i *Interpreter) checkDependents(name string, value ast.Node) error {
+	updated := make(map[*memoized]map[string]int)
+	for memoName, memo := range i.memos {
+		if _, found := memo.deps[name]; !found || memoName == name {
+			continue
+		}
+
+		current, _ := i.names.Get(memoName)
+		closure, ok := current.(ast.Closure)
+		if !ok || !isMemo(closure.Clauses) {
+			delete(i.memos, memoName)
+			continue
+		}
+
+		deps, err := i.checkPure(memoName, closure.Clauses, &binding{name: name, value: value})
+		if err != nil {
+			return fmt.Errorf("cannot redefine %s: %w", name, err)
+		}
+
+		updated[memo] = deps
+	}
+
+	for memo, deps := range updated {
+		memo.deps = deps
+	}
+
+	return nil
+}
+
+func isMemo(clauses []ast.FDef) bool {
+	for _, clause := range clauses {
+		if clause.Memo {
+			return true
+		}
+	}
+
+	return false
+}
+
+// memoKey encodes the arguments into the key of the cache, telling integers
+// from floats. Other values aren't comparable, so calls with them aren't cached
+func memoKey(args []ast.Node) (string, bool) {
+	var b strings.Builder
+	for _, arg := range args {
+		switch value := arg.(type) {
+		case ast.Integer:
+			b.WriteString("i" + strconv.FormatInt(int64(value), 10) + ",")
+		case ast.Float:
+			b.WriteString("f" + strconv.FormatFloat(value, 'g', -1, 64) + ",")
+		default:
+			return "", false
+		}
+	}
+
+	return b.String(), true
+}
+
+// purity checks whether results of the function depend on its arguments only,
+// so they can be cached. Bodies of such functions don't assign to outer names,
+// and refer only to pure builtins, constants and other pure functions. Definitions
+// made by the body are local to the call, so they are allowed
+type purity struct {
+	i *Interpreter
+	// checked are names of functions being checked or found pure already
+	checked map[string]bool
+	// deps are the names referred to, along with levels of their scopes
+	deps map[string]int
+	// pending is the binding about to be made, if any
+	pending *binding
+}
+
+type binding struct {
+	name  string
+	value ast.Node
+}
+
+// checkPure checks the function, as if the pending binding was made. Names it
+// depends on are returned
+func (i *Interpreter) checkPure(name string, clauses []ast.FDef, pending *binding) (map[string]int, error) {
+	p := purity{
+		i:       i,
+		checked: map[string]bool{name: true},
+		deps:    map[string]int{name: i.names.Depth()},
+		pending: pending,
+	}
+	if err := p.function(clauses); err != nil {
+		return nil, fmt.Errorf("cannot memoize %s: %w", name, err)
+	}
+
+	return p.deps, nil
+}
+
+func (p purity) function(clauses []ast.FDef) error {
+	for _, clause := range clauses {
+		if err := p.clause(clause, map[string]bool{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p purity) clause(fdef ast.FDef, locals map[string]bool) error {
+	scope := copyLocals(locals)
+	for index, arg := range fdef.Args {
+		if fdef.Defaults != nil && fdef.Defaults[index] != nil {
+			if err := p.node(fdef.Defaults[index], scope, false); err != nil {
+				return err
+			}
+		}
+
+		scope[arg] = true
+	}
+
+	for _, node := range []ast.Node{fdef.Guard, fdef.Body} {
+		if node == nil {
+			continue
+		}
+
+		if err := p.node(node, scope, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// node checks the expression. Unknowns are names, which may be left unbound, as
+// they are bound by a form the expression is passed to, like by solve
+func (p purity) node(node ast.Node, locals map[string]bool, unknowns bool) error {
+	switch n := node.(type) {
+	case ast.ID:
+		return p.name(n, locals, unknowns)
+	case ast.UnOp:
+		return p.node(n.Value, locals, unknowns)
+	case ast.BinOp:
+		if err := p.node(n.Left, locals, unknowns); err != nil {
+			return err
+		}
+
+		return p.node(n.Right, locals, unknowns)
+	case ast.Equation:
+		if err := p.node(n.Left, locals, unknowns); err != nil {
+			return err
+		}
+
+		return p.node(n.Right, locals, unknowns)
+	case ast.FCall:
+		if err := p.node(n.Target, locals, unknowns); err != nil {
+			return err
+		}
+
+		if id, ok := n.Target.(ast.ID); ok && !locals[id] {
+			target, _ := p.i.lookup(id)
+			_, isForm := target.(ast.Form)
+			unknowns = unknowns || isForm
+		}
+
+		for _, arg := range n.Args {
+			if err := p.node(arg, locals, unknowns); err != nil {
+				return err
+			}
+		}
+	case ast.NamedArg:
+		return p.node(n.Value, locals, unknowns)
+	case ast.Spread:
+		return p.node(n.Value, locals, unknowns)
+	case ast.Def:
+		if err := p.node(n.Value, locals, unknowns); err != nil {
+			return err
+		}
+
+		locals[n.Name] = true
+	case ast.FDef:
+		locals[n.Name] = true
+
+		return p.clause(n, locals)
+	case ast.Assign:
+		if !locals[n.Name] {
+			return fmt.Errorf("assigns to %s", n.Name)
+		}
+
+		return p.node(n.Value, locals, unknowns)
+	case ast.Block:
+		scope := copyLocals(locals)
+		for _, stmt := range n.Body {
+			if err := p.node(stmt, scope, unknowns); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p purity) name(name string, locals map[string]bool, unknowns bool) error {
+	if locals[name] || p.checked[name] {
+		return nil
+	}
+
+	value, found := p.i.names.Get(name)
+	level := p.i.names.Level(name)
+	if p.pending != nil && p.pending.name == name {
+		value, found, level = p.pending.value, true, p.i.names.Depth()
+	}
+
+	switch {
+	case !found && unknowns:
+		return nil
+	case !found:
+		return fmt.Errorf("refers to undefined name %s", name)
+	}
+
+	p.deps[name] = level
+	if _, ok := value.(constant); ok {
+		return nil
+	}
+	switch fn := value.(type) {
+	case ast.Closure:
+		p.checked[name] = true
+		if err := p.function(fn.Clauses); err != nil {
+			return fmt.Errorf("calls %s, which %w", name, err)
+		}
+
+		return nil
+	case ast.Impure:
+		return fmt.Errorf("calls impure builtin %s", name)
+	case ast.Function, ast.NamedFunction, ast.Form:
+		// functions written in Go are pure, unless marked as impure
+		return nil
+	}
+
+	if level == builtinsLevel {
+		return nil
+	}
+
+	return fmt.Errorf("refers to variable %s", name)
+}
+
+func copyLocals(locals map[string]bool) map[string]bool {
+	scope := make(map[string]bool, len(locals))
+	for name := range locals {
+		scope[name] = true
+	}
+
+	return scope
+}
@@ -0,0 +1,242 @@
+package numeric
+
+import (
+	"calculator/backend/arith"
+	"calculator/backend/symbolic"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse/ast"
+	"fmt"
+)
+
+// defaultBound limits the interval roots are searched in, unless specified
+const defaultBound = 1000
+
+// Builtins returns the numeric methods exposed to the language
+func Builtins() map[string]ast.Node {
+	return map[string]ast.Node{
+		"solve":     ast.Form(solve),
+		"integrate": ast.NamedFunction{Names: []string{"f", "a", "b", "tolerance"}, Fn: integrate},
+		"nsum":      ast.Limited{Names: []string{"f", "a", "b"}, Fn: nsum},
+	}
+}
+
+// solve finds roots of an equation in the unknown:
+//
+//	solve(equation, unknown[, lo, hi[, tolerance[, maxiter]]])
+//
+// An expression may be passed instead of the equation, meaning it equals zero.
+// All the roots found in the interval are returned as a list
+func solve(env ast.Env, args ...ast.Node) (ast.Node, error) {
+	if len(args) < 2 || len(args) == 3 || len(args) > 6 {
+		return nil, fmt.Errorf("wanted 2, 4, 5 or 6 args, got %d instead", len(args))
+	}
+
+	expr := args[0]
+	if eq, ok := expr.(ast.Equation); ok {
+		expr = ast.BinOp{Op: lex.OpMinus, Left: eq.Left, Right: eq.Right}
+	}
+
+	unknown, ok := args[1].(ast.ID)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as unknown", args[1])
+	}
+
+	numbers := make([]float64, len(args)-2)
+	for i, arg := range args[2:] {
+		value, err := env.Evaluate(arg, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if numbers[i], err = toFloat(value); err != nil {
+			return nil, err
+		}
+	}
+
+	lo, hi := float64(-defaultBound), float64(defaultBound)
+	if len(numbers) >= 2 {
+		lo, hi = numbers[0], numbers[1]
+		if lo >= hi {
+			return nil, fmt.Errorf("empty interval: [%g, %g]", lo, hi)
+		}
+
+		numbers = numbers[2:]
+	}
+
+	opts, err := options(numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := Roots(func(x float64) (float64, error) {
+		value, err := env.Evaluate(expr, map[string]ast.Node{unknown: x})
+		if err != nil {
+			return 0, err
+		}
+
+		y, err := toFloat(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s = %g: %w", unknown, x, err)
+		}
+
+		return y, nil
+	}, lo, hi, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make(ast.List, len(roots))
+	for i, root := range roots {
+		list[i] = root
+	}
+
+	return list, nil
+}
+
+// integrate computes the definite integral of a function:
+//
+//	integrate(f, a, b[, tolerance])
+//
+// The result is a list of the value and its absolute error estimate
+func integrate(args ...ast.Node) (ast.Node, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, fmt.Errorf("wanted 3 or 4 args, got %d instead", len(args))
+	}
+
+	f, ok := ast.Callee(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot integrate %v: not a function", args[0])
+	}
+
+	numbers := make([]float64, len(args)-1)
+	for i, arg := range args[1:] {
+		var err error
+		if numbers[i], err = toFloat(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	opts, err := options(numbers[2:])
+	if err != nil {
+		return nil, err
+	}
+
+	value, estimate, err := Integrate(func(x float64) (float64, error) {
+		y, err := f(x)
+		if err != nil {
+			return 0, err
+		}
+
+		return toFloat(y)
+	}, numbers[0], numbers[1], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.List{value, estimate}, nil
+}
+
+// nsum sums up values of the function over integers in [a, b]. Every term is a
+// step of the evaluation:
+//
+//	nsum(f, a, b)
+func nsum(budget ast.Budget, args ...ast.Node) (ast.Node, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("wanted 3 args, got %d instead", len(args))
+	}
+
+	f, ok := ast.Callee(args[0])
+	if !ok {
+		return nil, fmt.Errorf("cannot sum %v: not a function", args[0])
+	}
+
+	a, ok := args[1].(ast.Integer)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as integer", args[1])
+	}
+
+	b, ok := args[2].(ast.Integer)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %v as integer", args[2])
+	}
+
+	var (
+		sum      ast.Integer
+		fraction ast.Float
+		isFloat  bool
+	)
+	// the loop stops at b explicitly, as k would wrap around past math.MaxInt64
+	for k := a; a <= b; k++ {
+		if err := budget.Step(1); err != nil {
+			return nil, err
+		}
+
+		value, err := f(k)
+		if err != nil {
+			return nil, fmt.Errorf("at %d: %w", k, err)
+		}
+
+		switch value := value.(type) {
+		case ast.Integer:
+			total, err := arith.Add(sum, value)
+			if err != nil {
+				return nil, err
+			}
+
+			sum = total.(ast.Integer)
+		case ast.Float:
+			fraction += value
+			isFloat = true
+		default:
+			return nil, fmt.Errorf("cannot use %v as number", value)
+		}
+
+		if k == b {
+			break
+		}
+	}
+
+	if isFloat {
+		return fraction + ast.Float(sum), nil
+	}
+
+	return sum, nil
+}
+
+// options parses optional tolerance and iterations limit
+func options(numbers []float64) (Options, error) {
+	opts := DefaultOptions()
+	if len(numbers) > 0 {
+		if numbers[0] <= 0 {
+			return opts, fmt.Errorf("tolerance must be positive, got %g", numbers[0])
+		}
+
+		opts.Tolerance = numbers[0]
+	}
+
+	if len(numbers) > 1 {
+		if numbers[1] < 1 || numbers[1] != float64(int(numbers[1])) {
+			return opts, fmt.Errorf("iterations limit must be a positive integer, got %g", numbers[1])
+		}
+
+		opts.MaxIter = int(numbers[1])
+	}
+
+	return opts, nil
+}
+
+func toFloat(node ast.Node) (float64, error) {
+	switch value := node.(type) {
+	case ast.Integer:
+		return float64(value), nil
+	case ast.Float:
+		return value, nil
+	case symbolic.Expr:
+		if constant, ok := value.Constant(); ok {
+			f, _ := constant.Float64()
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("cannot use %v as number", node)
+}
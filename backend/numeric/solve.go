@@ -0,0 +1,197 @@
+package numeric
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	DefaultTolerance = 1e-12
+	DefaultMaxIter   = 100
+
+	// samples is how many subintervals the search interval is split into. Roots
+	// closer to each other than a single subinterval may be missed
+	samples = 10000
+)
+
+// Options tunes iterative methods
+type Options struct {
+	// Tolerance is the relative precision, after which the method stops
+	Tolerance float64
+	// MaxIter is how many iterations are allowed until the method gives up
+	MaxIter int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Tolerance: DefaultTolerance,
+		MaxIter:   DefaultMaxIter,
+	}
+}
+
+// Roots returns all the real roots of f found in [lo, hi] in ascending order.
+// The interval is sampled, and every subinterval where f changes its sign is
+// refined by Newton method, falling back to bisection whenever a step leaves
+// the bracket. Local minimums of |f| are tried by unbracketed Newton method,
+// so roots of even multiplicity are also found
+func Roots(f func(float64) (float64, error), lo, hi float64, opts Options) ([]float64, error) {
+	step := (hi - lo) / samples
+	xs := make([]float64, samples+1)
+	ys := make([]float64, samples+1)
+	for k := range xs {
+		xs[k] = lo + float64(k)*step
+		y, err := f(xs[k])
+		if err != nil {
+			return nil, err
+		}
+
+		ys[k] = y
+	}
+
+	var (
+		roots   []float64
+		lastErr error
+	)
+	for k := 0; k < samples; k++ {
+		a, b, fa, fb := xs[k], xs[k+1], ys[k], ys[k+1]
+		switch {
+		case math.IsNaN(fa) || math.IsNaN(fb):
+		case fa == 0:
+			roots = append(roots, a)
+		case fb != 0 && math.Signbit(fa) != math.Signbit(fb):
+			root, err := bracketed(f, a, b, fa, fb, opts)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			roots = append(roots, root)
+		case k > 0 && math.Abs(fa) < math.Abs(ys[k-1]) && math.Abs(fa) <= math.Abs(fb):
+			root, err := newton(f, a, opts)
+			if err != nil || root < xs[k-1] || root > b {
+				continue
+			}
+
+			roots = append(roots, root)
+		}
+	}
+
+	if ys[samples] == 0 {
+		roots = append(roots, xs[samples])
+	}
+
+	if len(roots) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+
+		return nil, fmt.Errorf("no roots found in [%g, %g]", lo, hi)
+	}
+
+	return dedup(roots, step, opts.Tolerance), nil
+}
+
+func bracketed(f func(float64) (float64, error), a, b, fa, fb float64, opts Options) (float64, error) {
+	lo, hi := a, b
+	x := (a + b) / 2
+	for i := 0; i < opts.MaxIter; i++ {
+		fx, err := f(x)
+		if err != nil {
+			return 0, err
+		}
+
+		if fx == 0 {
+			return x, nil
+		}
+
+		if math.Signbit(fx) == math.Signbit(fa) {
+			a, fa = x, fx
+		} else {
+			b = x
+		}
+
+		next := (a + b) / 2
+		if d, err := derivative(f, x); err == nil && d != 0 {
+			if newton := x - fx/d; newton > a && newton < b {
+				next = newton
+			}
+		}
+
+		if math.Abs(next-x) <= opts.Tolerance*(1+math.Abs(x)) || b-a <= opts.Tolerance*(1+math.Abs(x)) {
+			return next, nil
+		}
+
+		x = next
+	}
+
+	return 0, fmt.Errorf("root in [%g, %g] did not converge after %d iterations", lo, hi, opts.MaxIter)
+}
+
+func newton(f func(float64) (float64, error), x float64, opts Options) (float64, error) {
+	for i := 0; i < opts.MaxIter; i++ {
+		fx, err := f(x)
+		if err != nil {
+			return 0, err
+		}
+
+		if fx == 0 {
+			return x, nil
+		}
+
+		d, err := derivative(f, x)
+		if err != nil {
+			return 0, err
+		}
+
+		if d == 0 || math.IsNaN(d) {
+			return 0, errors.New("derivative vanished")
+		}
+
+		next := x - fx/d
+		if math.Abs(next-x) <= opts.Tolerance*(1+math.Abs(x)) {
+			return next, nil
+		}
+
+		x = next
+	}
+
+	return 0, fmt.Errorf("root did not converge after %d iterations", opts.MaxIter)
+}
+
+// derivative approximates f'(x) by the central difference
+func derivative(f func(float64) (float64, error), x float64) (float64, error) {
+	h := 1e-6 * math.Max(1, math.Abs(x))
+	right, err := f(x + h)
+	if err != nil {
+		return 0, err
+	}
+
+	left, err := f(x - h)
+	if err != nil {
+		return 0, err
+	}
+
+	return (right - left) / (2 * h), nil
+}
+
+// dedup sorts the roots, merging the ones closer than a single step and snapping
+// the ones close to integers
+func dedup(roots []float64, step, tolerance float64) []float64 {
+	sort.Float64s(roots)
+	unique := roots[:0]
+	for _, root := range roots {
+		if rounded := math.Round(root); math.Abs(root-rounded) <= tolerance*(1+math.Abs(root)) {
+			root = rounded
+		}
+
+		if len(unique) > 0 && root-unique[len(unique)-1] < step {
+			continue
+		}
+
+		unique = append(unique, root)
+	}
+
+	return unique
+}
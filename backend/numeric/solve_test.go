@@ -0,0 +1,67 @@
+package numeric
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoots(t *testing.T) {
+	t.Run("sign changes", func(t *testing.T) {
+		roots, err := Roots(func(x float64) (float64, error) {
+			return x*x*x - x, nil
+		}, -10, 10, DefaultOptions())
+		require.NoError(t, err)
+		require.Equal(t, []float64{-1, 0, 1}, roots)
+	})
+
+	t.Run("double root", func(t *testing.T) {
+		roots, err := Roots(func(x float64) (float64, error) {
+			return (x - 0.05) * (x - 0.05), nil
+		}, -1, 1, DefaultOptions())
+		require.NoError(t, err)
+		require.Len(t, roots, 1)
+		require.InDelta(t, 0.05, roots[0], 1e-6)
+	})
+
+	t.Run("no roots", func(t *testing.T) {
+		_, err := Roots(func(x float64) (float64, error) {
+			return x*x + 1, nil
+		}, -1, 1, DefaultOptions())
+		require.EqualError(t, err, "no roots found in [-1, 1]")
+	})
+
+	t.Run("not converged", func(t *testing.T) {
+		_, err := Roots(func(x float64) (float64, error) {
+			return math.Cbrt(x - 0.512345), nil
+		}, 0, 1, Options{Tolerance: 1e-15, MaxIter: 2})
+		require.ErrorContains(t, err, "did not converge after 2 iterations")
+	})
+}
+
+func TestIntegrate(t *testing.T) {
+	t.Run("polynomial", func(t *testing.T) {
+		value, estimate, err := Integrate(func(x float64) (float64, error) {
+			return x * x, nil
+		}, 0, 3, DefaultOptions())
+		require.NoError(t, err)
+		require.InDelta(t, 9, value, 1e-12)
+		require.Less(t, estimate, 1e-10)
+	})
+
+	t.Run("singularity at the end", func(t *testing.T) {
+		value, _, err := Integrate(func(x float64) (float64, error) {
+			return 1 / math.Sqrt(x), nil
+		}, 0, 1, Options{Tolerance: 1e-8})
+		require.NoError(t, err)
+		require.InDelta(t, 2, value, 1e-6)
+	})
+
+	t.Run("divergent", func(t *testing.T) {
+		_, _, err := Integrate(func(x float64) (float64, error) {
+			return 1 / x, nil
+		}, 0, 1, DefaultOptions())
+		require.ErrorContains(t, err, "integral did not converge")
+	})
+}
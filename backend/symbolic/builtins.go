@@ -0,0 +1,60 @@
+package symbolic
+
+import (
+	"calculator/frontend/parse/ast"
+	"fmt"
+)
+
+// Builtins returns functions exposing the simplifier to the language
+func Builtins() map[string]ast.Node {
+	return map[string]ast.Node{
+		"simplify": transform(Simplify),
+		"expand":   transform(Expand),
+		"factor": transform(func(_ ast.Budget, e Expr) (Expr, error) {
+			return Factor(e), nil
+		}),
+	}
+}
+
+func transform(fn func(ast.Budget, Expr) (Expr, error)) ast.Limited {
+	return ast.Limited{Fn: func(budget ast.Budget, args ...ast.Node) (ast.Node, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("wanted 1 arg, got %d instead", len(args))
+		}
+
+		expr, err := FromNode(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if expr, err = fn(budget, expr); err != nil {
+			return nil, err
+		}
+
+		return Node(expr), nil
+	}}
+}
+
+// FromNode converts an evaluated value into an expression
+func FromNode(node ast.Node) (Expr, error) {
+	switch value := node.(type) {
+	case Expr:
+		return value, nil
+	case ast.Integer:
+		return Int(value), nil
+	case ast.Float:
+		return Float(value)
+	}
+
+	return Expr{}, fmt.Errorf("cannot use %v in symbolic expression", node)
+}
+
+// Node converts the expression into a plain integer if it has no symbols and
+// its value is integral. Otherwise, the expression is returned as is
+func Node(e Expr) ast.Node {
+	if value, ok := e.Constant(); ok && value.IsInt() && value.Num().IsInt64() {
+		return value.Num().Int64()
+	}
+
+	return e
+}
@@ -0,0 +1,509 @@
+package symbolic
+
+import (
+	"calculator/frontend/parse/ast"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Expr is a symbolic expression kept in a canonical form: a sum of terms, each of
+// them being a rational coefficient multiplied by a product of powers. Like terms
+// are always collected and products are expanded, so equal polynomials are
+// represented equally. Powers of sums are kept as they are until expanded
+type Expr struct {
+	terms []term
+}
+
+type term struct {
+	coef *big.Rat
+	mono monomial
+}
+
+// monomial is a product of factors, sorted by their keys
+type monomial []factor
+
+type factor struct {
+	base atom
+	exp  int
+}
+
+// atom is an indivisible base of a factor
+type atom interface {
+	// key identifies the atom. It also defines the order of factors in a monomial
+	key() string
+	String() string
+}
+
+type symbol string
+
+func (s symbol) key() string {
+	return "0" + string(s)
+}
+
+func (s symbol) String() string {
+	return string(s)
+}
+
+// power is a power with a non-integer exponent, which can't be represented
+// by a factor
+type power struct {
+	base, exp Expr
+}
+
+func (p power) key() string {
+	return "1" + p.String()
+}
+
+func (p power) String() string {
+	return operand(p.base) + "^" + operand(p.exp)
+}
+
+// group is a sum used as a single factor, like in (a + b)^2
+type group struct {
+	sum Expr
+}
+
+func (g group) key() string {
+	return "2" + g.String()
+}
+
+func (g group) String() string {
+	return "(" + g.sum.String() + ")"
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+// Var returns an expression consisting of a single symbol
+func Var(name string) Expr {
+	return Expr{terms: []term{{
+		coef: big.NewRat(1, 1),
+		mono: monomial{{base: symbol(name), exp: 1}},
+	}}}
+}
+
+// Const returns a constant expression
+func Const(value *big.Rat) Expr {
+	if value.Sign() == 0 {
+		return Expr{}
+	}
+
+	return Expr{terms: []term{{coef: new(big.Rat).Set(value)}}}
+}
+
+// Int returns a constant integer expression
+func Int(value int64) Expr {
+	return Const(big.NewRat(value, 1))
+}
+
+// Float returns a constant expression out of the shortest decimal representation
+// of the value, so 0.1 results in 1/10 instead of its binary approximation
+func Float(value float64) (Expr, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Expr{}, errors.New("cannot use " + strconv.FormatFloat(value, 'g', -1, 64) + " in expression")
+	}
+
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64))
+
+	return Const(r), nil
+}
+
+// Constant returns the value of the expression, if it contains no symbols
+func (e Expr) Constant() (*big.Rat, bool) {
+	switch {
+	case len(e.terms) == 0:
+		return new(big.Rat), true
+	case len(e.terms) == 1 && len(e.terms[0].mono) == 0:
+		return new(big.Rat).Set(e.terms[0].coef), true
+	}
+
+	return nil, false
+}
+
+// Bits returns the largest bit length among numerators and denominators of the
+// coefficients
+func (e Expr) Bits() (size int) {
+	for _, t := range e.terms {
+		for _, n := range []*big.Int{t.coef.Num(), t.coef.Denom()} {
+			if n.BitLen() > size {
+				size = n.BitLen()
+			}
+		}
+	}
+
+	return size
+}
+
+// Equal reports whether both expressions have the same canonical form
+func (e Expr) Equal(other Expr) bool {
+	return len(Sub(e, other).terms) == 0
+}
+
+func (e Expr) String() string {
+	if len(e.terms) == 0 {
+		return "0"
+	}
+
+	var b strings.Builder
+	for i, t := range e.terms {
+		str, negative := t.format()
+		switch {
+		case i == 0 && negative:
+			b.WriteString("-")
+		case i > 0 && negative:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+
+		b.WriteString(str)
+	}
+
+	return b.String()
+}
+
+func Add(a, b Expr) Expr {
+	terms := make([]term, 0, len(a.terms)+len(b.terms))
+	terms = append(terms, a.terms...)
+	terms = append(terms, b.terms...)
+
+	return collect(terms)
+}
+
+func Sub(a, b Expr) Expr {
+	return Add(a, Neg(b))
+}
+
+func Neg(e Expr) Expr {
+	terms := make([]term, len(e.terms))
+	for i, t := range e.terms {
+		terms[i] = term{coef: new(big.Rat).Neg(t.coef), mono: t.mono}
+	}
+
+	return Expr{terms: terms}
+}
+
+// Mul multiplies the expressions, expanding the product
+func Mul(a, b Expr) Expr {
+	var terms []term
+	for _, x := range a.terms {
+		for _, y := range b.terms {
+			terms = append(terms, mulTerms(x, y)...)
+		}
+	}
+
+	return collect(terms)
+}
+
+func Div(a, b Expr) (Expr, error) {
+	switch {
+	case len(b.terms) == 0:
+		return Expr{}, errDivisionByZero
+	case len(b.terms) == 1:
+		return Mul(a, Expr{terms: []term{invert(b.terms[0])}}), nil
+	case a.Equal(b):
+		return Int(1), nil
+	}
+
+	return Mul(a, Expr{terms: []term{{
+		coef: big.NewRat(1, 1),
+		mono: monomial{{base: group{b}, exp: -1}},
+	}}}), nil
+}
+
+// Pow raises the base to the power. Powers of constants are rejected before they
+// are computed, if their numbers would be larger than the budget allows
+func Pow(budget ast.Budget, base, exp Expr) (Expr, error) {
+	if n, ok := exp.integer(); ok {
+		return powInt(budget, base, n)
+	}
+
+	if b, ok := base.Constant(); ok {
+		if e, ok := exp.Constant(); ok {
+			fb, _ := b.Float64()
+			fe, _ := e.Float64()
+
+			return Float(math.Pow(fb, fe))
+		}
+	}
+
+	return Expr{terms: []term{{
+		coef: big.NewRat(1, 1),
+		mono: monomial{{base: power{base, exp}, exp: 1}},
+	}}}, nil
+}
+
+// integer returns the value of the expression if it is a constant integer, small
+// enough to be used as an exponent
+func (e Expr) integer() (int, bool) {
+	value, ok := e.Constant()
+	if !ok || !value.IsInt() || !value.Num().IsInt64() {
+		return 0, false
+	}
+
+	n := value.Num().Int64()
+	if n > math.MaxInt32 || n < math.MinInt32 {
+		return 0, false
+	}
+
+	return int(n), true
+}
+
+func powInt(budget ast.Budget, base Expr, n int) (Expr, error) {
+	switch {
+	case n == 0:
+		return Int(1), nil
+	case len(base.terms) == 0:
+		if n < 0 {
+			return Expr{}, errDivisionByZero
+		}
+
+		return Expr{}, nil
+	case len(base.terms) == 1:
+		t := base.terms[0]
+		if err := budget.CheckBits(powBits(t.coef, n)); err != nil {
+			return Expr{}, err
+		}
+
+		mono := make(monomial, len(t.mono))
+		for i, f := range t.mono {
+			mono[i] = factor{base: f.base, exp: f.exp * n}
+		}
+
+		return Expr{terms: []term{{coef: ratPow(t.coef, n), mono: mono}}}, nil
+	case n == 1:
+		return base, nil
+	}
+
+	return Expr{terms: []term{{
+		coef: big.NewRat(1, 1),
+		mono: monomial{{base: group{base}, exp: n}},
+	}}}, nil
+}
+
+// powBits estimates the bit length of numbers of the rational raised to the power,
+// never overestimating it. The estimate saturates at math.MaxInt
+func powBits(r *big.Rat, n int) int {
+	if n < 0 {
+		n = -n
+	}
+
+	size := 1
+	for _, x := range []*big.Int{r.Num(), r.Denom()} {
+		// x^n takes at least (bits - 1) * n + 1 bits, as x is at least 2^(bits - 1)
+		bits := x.BitLen() - 1
+		switch {
+		case bits > 0 && n > (math.MaxInt-1)/bits:
+			return math.MaxInt
+		case bits*n+1 > size:
+			size = bits*n + 1
+		}
+	}
+
+	return size
+}
+
+func ratPow(r *big.Rat, n int) *big.Rat {
+	exp := big.NewInt(int64(n))
+	if n < 0 {
+		exp.Neg(exp)
+	}
+
+	num := new(big.Int).Exp(r.Num(), exp, nil)
+	den := new(big.Int).Exp(r.Denom(), exp, nil)
+	if n < 0 {
+		num, den = den, num
+	}
+
+	return new(big.Rat).SetFrac(num, den)
+}
+
+func invert(t term) term {
+	mono := make(monomial, len(t.mono))
+	for i, f := range t.mono {
+		mono[i] = factor{base: f.base, exp: -f.exp}
+	}
+
+	return term{coef: new(big.Rat).Inv(t.coef), mono: mono}
+}
+
+// mulTerms multiplies two terms. Sums being multiplied by the product are expanded,
+// therefore the result may consist of multiple terms
+func mulTerms(x, y term) []term {
+	exps := make(map[string]factor, len(x.mono)+len(y.mono))
+	for _, f := range append(append(monomial(nil), x.mono...), y.mono...) {
+		if prev, found := exps[f.base.key()]; found {
+			f.exp += prev.exp
+		}
+
+		exps[f.base.key()] = f
+	}
+
+	product := term{coef: new(big.Rat).Mul(x.coef, y.coef)}
+	var sums []Expr
+	for _, f := range exps {
+		switch {
+		case f.exp == 0:
+		case f.exp == 1 && isGroup(f.base):
+			sums = append(sums, f.base.(group).sum)
+		default:
+			product.mono = append(product.mono, f)
+		}
+	}
+
+	sort.Slice(product.mono, func(i, j int) bool {
+		return product.mono[i].base.key() < product.mono[j].base.key()
+	})
+
+	result := Expr{terms: []term{product}}
+	for _, sum := range sums {
+		result = Mul(result, sum)
+	}
+
+	return result.terms
+}
+
+// collect sums up coefficients of like terms, dropping zeroes
+func collect(terms []term) Expr {
+	indices := make(map[string]int, len(terms))
+	var collected []term
+	for _, t := range terms {
+		key := t.mono.key()
+		if i, found := indices[key]; found {
+			collected[i].coef = new(big.Rat).Add(collected[i].coef, t.coef)
+			continue
+		}
+
+		indices[key] = len(collected)
+		collected = append(collected, term{coef: new(big.Rat).Set(t.coef), mono: t.mono})
+	}
+
+	nonzero := collected[:0]
+	for _, t := range collected {
+		if t.coef.Sign() != 0 {
+			nonzero = append(nonzero, t)
+		}
+	}
+
+	sortTerms(nonzero)
+
+	return Expr{terms: nonzero}
+}
+
+// sortTerms orders terms by descending degree, so constants go last
+func sortTerms(terms []term) {
+	sort.SliceStable(terms, func(i, j int) bool {
+		if di, dj := terms[i].mono.degree(), terms[j].mono.degree(); di != dj {
+			return di > dj
+		}
+
+		return terms[i].mono.less(terms[j].mono)
+	})
+}
+
+// less orders monomials of the same degree lexicographically, so variables
+// earlier in the alphabet with higher powers go first
+func (m monomial) less(other monomial) bool {
+	for i := 0; i < len(m) && i < len(other); i++ {
+		if a, b := m[i].base.key(), other[i].base.key(); a != b {
+			return a < b
+		}
+
+		if m[i].exp != other[i].exp {
+			return m[i].exp > other[i].exp
+		}
+	}
+
+	return len(m) < len(other)
+}
+
+func (m monomial) key() string {
+	keys := make([]string, len(m))
+	for i, f := range m {
+		keys[i] = f.base.key() + "^" + strconv.Itoa(f.exp)
+	}
+
+	return strings.Join(keys, "*")
+}
+
+func (m monomial) degree() (degree int) {
+	for _, f := range m {
+		degree += f.exp
+	}
+
+	return degree
+}
+
+// format returns the term without its sign, and whether the sign is negative
+func (t term) format() (str string, negative bool) {
+	coef := new(big.Rat).Abs(t.coef)
+	var numer, denom []string
+	if !coef.IsInt() {
+		denom = append(denom, coef.Denom().String())
+	}
+
+	if coef.Num().Cmp(big.NewInt(1)) != 0 || len(t.mono) == 0 {
+		numer = append(numer, coef.Num().String())
+	}
+
+	for _, f := range t.mono {
+		if f.exp > 0 {
+			numer = append(numer, f.format(f.exp))
+		} else {
+			denom = append(denom, f.format(-f.exp))
+		}
+	}
+
+	if len(numer) == 0 {
+		numer = append(numer, "1")
+	}
+
+	str = strings.Join(numer, "*")
+	switch {
+	case len(denom) == 1:
+		str += "/" + denom[0]
+	case len(denom) > 1:
+		str += "/(" + strings.Join(denom, "*") + ")"
+	}
+
+	return str, t.coef.Sign() < 0
+}
+
+func (f factor) format(exp int) string {
+	base := f.base.String()
+	if exp == 1 {
+		return base
+	}
+
+	if _, ok := f.base.(power); ok {
+		base = "(" + base + ")"
+	}
+
+	return base + "^" + strconv.Itoa(exp)
+}
+
+// operand returns the expression, wrapped in parenthesis unless it is a symbol
+// or a non-negative integer
+func operand(e Expr) string {
+	if value, ok := e.Constant(); ok && value.IsInt() && value.Sign() >= 0 {
+		return e.String()
+	}
+
+	if len(e.terms) == 1 && len(e.terms[0].mono) == 1 && e.terms[0].coef.Cmp(big.NewRat(1, 1)) == 0 {
+		if f := e.terms[0].mono[0]; f.exp == 1 {
+			if _, ok := f.base.(symbol); ok {
+				return e.String()
+			}
+		}
+	}
+
+	return "(" + e.String() + ")"
+}
+
+func isGroup(a atom) bool {
+	_, ok := a.(group)
+	return ok
+}
@@ -0,0 +1,50 @@
+package symbolic
+
+import (
+	"calculator/frontend/parse/ast"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpr(t *testing.T) {
+	x, y := Var("x"), Var("y")
+
+	t.Run("collect like terms", func(t *testing.T) {
+		e := Add(Mul(Int(2), x), Sub(y, Mul(x, Int(2))))
+		require.Equal(t, "y", e.String())
+	})
+
+	t.Run("decimal constant", func(t *testing.T) {
+		c, err := Float(0.1)
+		require.NoError(t, err)
+		require.Equal(t, "1/10", c.String())
+	})
+
+	t.Run("power of sum", func(t *testing.T) {
+		sum := Add(x, Int(1))
+		squared, err := Pow(ast.Unlimited, sum, Int(2))
+		require.NoError(t, err)
+		require.Equal(t, "(x + 1)^2", squared.String())
+		expanded, err := Expand(ast.Unlimited, squared)
+		require.NoError(t, err)
+		require.Equal(t, "x^2 + 2*x + 1", expanded.String())
+
+		quotient, err := Div(squared, sum)
+		require.NoError(t, err)
+		require.Equal(t, "x + 1", quotient.String())
+	})
+
+	t.Run("division by zero", func(t *testing.T) {
+		_, err := Div(x, Sub(y, y))
+		require.EqualError(t, err, "division by zero")
+	})
+
+	t.Run("factor", func(t *testing.T) {
+		e := Add(Mul(Int(6), Mul(x, y)), Mul(Int(9), Mul(x, x)))
+		require.Equal(t, "3*x*(3*x + 2*y)", Factor(e).String())
+		expanded, err := Expand(ast.Unlimited, Factor(e))
+		require.NoError(t, err)
+		require.True(t, expanded.Equal(e))
+	})
+}
@@ -0,0 +1,132 @@
+package symbolic
+
+import (
+	"calculator/frontend/parse/ast"
+	"math/big"
+)
+
+// Expand multiplies out powers of sums, like (a + b)^2. Sums in denominators
+// are left untouched. Every product of two terms is a step of the budget, and
+// the expansion fails once its numbers get larger than the budget allows
+func Expand(budget ast.Budget, e Expr) (Expr, error) {
+	var result Expr
+	for _, t := range e.terms {
+		expanded := Expr{terms: []term{{coef: t.coef}}}
+		for _, f := range t.mono {
+			g, ok := f.base.(group)
+			if !ok || f.exp < 1 {
+				expanded = Mul(expanded, Expr{terms: []term{{coef: big.NewRat(1, 1), mono: monomial{f}}}})
+				continue
+			}
+
+			sum, err := Expand(budget, g.sum)
+			if err != nil {
+				return Expr{}, err
+			}
+
+			for i := 0; i < f.exp; i++ {
+				if err = budget.Step(len(expanded.terms) * len(sum.terms)); err != nil {
+					return Expr{}, err
+				}
+
+				expanded = Mul(expanded, sum)
+				if err = budget.CheckBits(expanded.Bits()); err != nil {
+					return Expr{}, err
+				}
+			}
+		}
+
+		result = Add(result, expanded)
+	}
+
+	return result, nil
+}
+
+// Factor takes the common constant and the common product of symbols out of
+// parenthesis, so 2x^2 + 4x results in 2*x*(x + 2)
+func Factor(e Expr) Expr {
+	if len(e.terms) < 2 {
+		return e
+	}
+
+	content := e.content()
+	common := e.commonMonomial()
+	if content.Cmp(big.NewRat(1, 1)) == 0 && len(common) == 0 {
+		return e
+	}
+
+	divisor := term{coef: content, mono: common}
+	rest := Mul(e, Expr{terms: []term{invert(divisor)}})
+	factored := append(monomial(nil), common...)
+	factored = append(factored, factor{base: group{rest}, exp: 1})
+
+	return Expr{terms: []term{{coef: content, mono: factored}}}
+}
+
+// Simplify returns either the expression or its fully expanded form, whichever
+// has less terms
+func Simplify(budget ast.Budget, e Expr) (Expr, error) {
+	expanded, err := Expand(budget, e)
+	if err != nil {
+		return Expr{}, err
+	}
+
+	if len(expanded.terms) <= len(e.terms) {
+		return expanded, nil
+	}
+
+	return e, nil
+}
+
+// content returns the greatest rational dividing all the coefficients. Its sign
+// matches the sign of the leading term
+func (e Expr) content() *big.Rat {
+	num, den := new(big.Int), big.NewInt(1)
+	for _, t := range e.terms {
+		num.GCD(nil, nil, num, new(big.Int).Abs(t.coef.Num()))
+		gcd := new(big.Int).GCD(nil, nil, den, t.coef.Denom())
+		den.Mul(den, new(big.Int).Quo(t.coef.Denom(), gcd))
+	}
+
+	content := new(big.Rat).SetFrac(num, den)
+	if e.terms[0].coef.Sign() < 0 {
+		content.Neg(content)
+	}
+
+	return content
+}
+
+// commonMonomial returns the product of the lowest positive powers of symbols
+// present in every term
+func (e Expr) commonMonomial() (common monomial) {
+	for _, f := range e.terms[0].mono {
+		if _, ok := f.base.(symbol); !ok || f.exp < 1 {
+			continue
+		}
+
+		exp := f.exp
+		for _, t := range e.terms[1:] {
+			other := t.mono.exp(f.base)
+			if other < exp {
+				exp = other
+			}
+		}
+
+		if exp > 0 {
+			common = append(common, factor{base: f.base, exp: exp})
+		}
+	}
+
+	return common
+}
+
+// exp returns the exponent of the atom in the monomial, or 0 if it isn't present
+func (m monomial) exp(base atom) int {
+	for _, f := range m {
+		if f.base.key() == base.key() {
+			return f.exp
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,212 @@
+// Package calculator embeds the calculator language into Go programs:
+//
+//	calc := calculator.New()
+//	if err := calc.Set("price", 120); err != nil { ... }
+//	total, err := calc.Eval("discount(x) -> x * 0.9\ndiscount(price)")
+//
+// Formulas evaluated repeatedly with different inputs are better compiled once:
+//
+//	program, err := calc.Compile("price * quantity")
+//	total, err := program.Eval(map[string]any{"price": 120, "quantity": 3})
+package calculator
+
+import (
+	"calculator/backend/functional"
+	"calculator/backend/interpret"
+	"calculator/backend/numeric"
+	"calculator/backend/symbolic"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse"
+	"calculator/frontend/parse/ast"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Calculator keeps the names defined by evaluated code and by Set between calls.
+// It isn't safe for concurrent use
+type Calculator struct {
+	interpreter *interpret.Interpreter
+}
+
+// New returns a calculator with the standard builtins
+func New() *Calculator {
+	return &Calculator{interpreter: interpret.NewInterpreter(Builtins())}
+}
+
+// Builtins returns the standard builtins, like solve, simplify and map
+func Builtins() map[string]ast.Node {
+	builtins := make(map[string]ast.Node)
+	for _, lib := range []map[string]ast.Node{
+		symbolic.Builtins(), numeric.Builtins(), functional.Builtins(),
+	} {
+		for name, builtin := range lib {
+			builtins[name] = builtin
+		}
+	}
+
+	return builtins
+}
+
+// Eval evaluates the source by a new calculator
+func Eval(src string) (Value, error) {
+	return New().Eval(src)
+}
+
+// Compile parses the source for a new calculator
+func Compile(src string) (*Program, error) {
+	return New().Compile(src)
+}
+
+// SetSymbolic toggles the symbolic mode, where names which aren't bound evaluate
+// to symbols instead of failing
+func (c *Calculator) SetSymbolic(enabled bool) {
+	c.interpreter.SetSymbolic(enabled)
+}
+
+// SetLimits bounds resources taken by every following evaluation. Exceeding them
+// results in an EvalError wrapping one of the Err*Limit errors
+func (c *Calculator) SetLimits(limits Limits) {
+	c.interpreter.SetLimits(limits)
+}
+
+// Eval evaluates the statements and returns the result of the last one. Names
+// defined by them stay visible to the following calls
+func (c *Calculator) Eval(src string) (Value, error) {
+	return c.EvalContext(context.Background(), src)
+}
+
+// EvalContext is like Eval, but aborts the evaluation once the context is done
+func (c *Calculator) EvalContext(ctx context.Context, src string) (Value, error) {
+	tree, err := parseSource(src)
+	if err != nil {
+		return Value{}, err
+	}
+
+	defer c.interpreter.Begin(ctx)()
+
+	var result ast.Node
+	for _, stmt := range tree {
+		if result, err = c.interpreter.EvaluateContext(ctx, stmt); err != nil {
+			return Value{}, &EvalError{Err: err}
+		}
+	}
+
+	return Value{result}, nil
+}
+
+// Compile parses the source once, so it can be evaluated many times
+func (c *Calculator) Compile(src string) (*Program, error) {
+	tree, err := parseSource(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{calc: c, tree: tree}, nil
+}
+
+// Set binds the name to the value, converted as described by ValueOf
+func (c *Calculator) Set(name string, value any) error {
+	v, err := ValueOf(value)
+	if err != nil {
+		return err
+	}
+
+	c.interpreter.Define(name, v.node)
+
+	return nil
+}
+
+// Get returns the value of the name. ErrUndefined is returned if there is no such name
+func (c *Calculator) Get(name string) (Value, error) {
+	value, found := c.interpreter.Lookup(name)
+	if !found {
+		return Value{}, fmt.Errorf("%w: %s", ErrUndefined, name)
+	}
+
+	return Value{value}, nil
+}
+
+// Call calls the function by the name. Arguments are converted as described
+// by ValueOf
+func (c *Calculator) Call(name string, args ...any) (Value, error) {
+	return c.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is like Call, but aborts the call once the context is done
+func (c *Calculator) CallContext(ctx context.Context, name string, args ...any) (Value, error) {
+	target, err := c.Get(name)
+	if err != nil {
+		return Value{}, err
+	}
+
+	fn, ok := ast.Callee(target.node)
+	if !ok {
+		return Value{}, &EvalError{Err: fmt.Errorf("cannot call %s: not a function", name)}
+	}
+
+	nodes := make([]ast.Node, len(args))
+	for i, arg := range args {
+		v, err := ValueOf(arg)
+		if err != nil {
+			return Value{}, err
+		}
+
+		nodes[i] = v.node
+	}
+
+	result, err := c.interpreter.Call(ctx, fn, nodes...)
+	if err != nil {
+		return Value{}, &EvalError{Err: err}
+	}
+
+	return Value{result}, nil
+}
+
+// Program is a parsed source, bound to the calculator it was compiled by
+type Program struct {
+	calc *Calculator
+	tree ast.Program
+}
+
+// Eval evaluates the program with the variables bound in addition to the names
+// of the calculator. Neither the variables nor names defined by the program
+// outlive the call
+func (p *Program) Eval(vars map[string]any) (Value, error) {
+	return p.EvalContext(context.Background(), vars)
+}
+
+// EvalContext is like Eval, but aborts the evaluation once the context is done
+func (p *Program) EvalContext(ctx context.Context, vars map[string]any) (Value, error) {
+	names := make(map[string]ast.Node, len(vars))
+	for name, value := range vars {
+		v, err := ValueOf(value)
+		if err != nil {
+			return Value{}, err
+		}
+
+		names[name] = v.node
+	}
+
+	result, err := p.calc.interpreter.EvaluateScoped(ctx, p.tree, names)
+	if err != nil {
+		return Value{}, &EvalError{Err: err}
+	}
+
+	return Value{result}, nil
+}
+
+func parseSource(src string) (ast.Program, error) {
+	tree, err := parse.NewParser(lex.NewLexer(src)).Parse()
+	if err != nil {
+		syntaxErr := &SyntaxError{Err: err}
+		var parseErr *parse.Error
+		if errors.As(err, &parseErr) {
+			syntaxErr.Line, syntaxErr.Column = parseErr.Pos.Line+1, parseErr.Pos.Char+1
+		}
+
+		return nil, syntaxErr
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,84 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculator(t *testing.T) {
+	t.Run("eval", func(t *testing.T) {
+		result, err := Eval("a -> 2\na^10")
+		require.NoError(t, err)
+		n, err := result.Int()
+		require.NoError(t, err)
+		require.Equal(t, int64(1024), n)
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		calc := New()
+		require.NoError(t, calc.Set("price", 120))
+		require.NoError(t, calc.Set("rate", 1.5))
+		_, err := calc.Eval("total -> price * rate")
+		require.NoError(t, err)
+
+		total, err := calc.Get("total")
+		require.NoError(t, err)
+		f, err := total.Float()
+		require.NoError(t, err)
+		require.Equal(t, 180.0, f)
+
+		_, err = calc.Get("missing")
+		require.ErrorIs(t, err, ErrUndefined)
+	})
+
+	t.Run("call", func(t *testing.T) {
+		calc := New()
+		_, err := calc.Eval("discount(x, rate) -> x - x*rate/100")
+		require.NoError(t, err)
+
+		result, err := calc.Call("discount", 200, 10)
+		require.NoError(t, err)
+		require.Equal(t, "180", result.String())
+
+		_, err = calc.Call("discount", 200)
+		var evalErr *EvalError
+		require.ErrorAs(t, err, &evalErr)
+	})
+
+	t.Run("program", func(t *testing.T) {
+		calc := New()
+		program, err := calc.Compile("subtotal -> price * quantity\nsubtotal + 5")
+		require.NoError(t, err)
+
+		for quantity, want := range []int64{5, 15, 25} {
+			result, err := program.Eval(map[string]any{"price": 10, "quantity": quantity})
+			require.NoError(t, err)
+			n, err := result.Int()
+			require.NoError(t, err)
+			require.Equal(t, want, n)
+		}
+
+		_, err = calc.Get("subtotal")
+		require.ErrorIs(t, err, ErrUndefined)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := Eval("1 +\n(2")
+		var syntaxErr *SyntaxError
+		require.ErrorAs(t, err, &syntaxErr)
+		require.Equal(t, 2, syntaxErr.Line)
+
+		_, err = Eval("price * 2")
+		var evalErr *EvalError
+		require.ErrorAs(t, err, &evalErr)
+
+		var typeErr *TypeError
+		require.ErrorAs(t, New().Set("name", "text"), &typeErr)
+
+		result, err := Eval("solve(x^2 = 4, x)")
+		require.NoError(t, err)
+		_, err = result.Int()
+		require.ErrorAs(t, err, &typeErr)
+	})
+}
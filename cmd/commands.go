@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"calculator/backend/interpret"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse"
+	"calculator/frontend/parse/ast"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	name, usage, help string
+	run               func(interpreter *interpret.Interpreter, arg string) error
+}
+
+// commands are the REPL meta-commands, prefixed by a colon
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "vars", help: "list defined variables", run: listVars},
+		{name: "funcs", help: "list defined functions", run: listFuncs},
+		{name: "tokens", usage: "<expr>", help: "print lexemes of the expression", run: printTokens},
+		{name: "ast", usage: "<expr>", help: "print the parse tree of the expression", run: printAST},
+		{name: "reset", help: "remove all the user definitions", run: reset},
+		{name: "cache", usage: "clear", help: "drop results cached by memo functions", run: cache},
+		{name: "load", usage: "<file>", help: "run the script", run: load},
+		{name: "save", usage: "<file>", help: "save the definitions as a script", run: save},
+		{name: "help", help: "list the commands", run: help},
+	}
+}
+
+func isCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+func runCommand(interpreter *interpret.Interpreter, line string) error {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), ":"), " ")
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(interpreter, strings.TrimSpace(arg))
+		}
+	}
+
+	return fmt.Errorf("unknown command: %s (see :help)", name)
+}
+
+// scriptCommands passes the script through, running meta-commands met in it in
+// place of their lines. Lines are passed one by one, as the parser needs them, so
+// commands run once the statements preceding them are evaluated. A failed command
+// ends the script, like a failed statement does
+type scriptCommands struct {
+	interpreter *interpret.Interpreter
+	script      *bufio.Reader
+	line        int
+	pending     string
+	// err is the failure of the command, which ended the script
+	err error
+}
+
+func newScriptCommands(interpreter *interpret.Interpreter, script io.Reader) *scriptCommands {
+	return &scriptCommands{interpreter: interpreter, script: bufio.NewReader(script)}
+}
+
+func (s *scriptCommands) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return 0, io.EOF
+		}
+
+		line, err := s.script.ReadString('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+
+		if isCommand(line) {
+			if err = runCommand(s.interpreter, line); err != nil {
+				s.err = fmt.Errorf("%s: %w", lex.Position{Line: s.line}, err)
+				return 0, io.EOF
+			}
+
+			// the line is left blank, so positions of the following ones stay right
+			line = "\n"
+		}
+
+		s.line++
+		s.pending = line
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+
+	return n, nil
+}
+
+func listVars(interpreter *interpret.Interpreter, _ string) error {
+	names := interpreter.Names()
+	for _, name := range sortedNames(names) {
+		if !isFunction(names[name]) {
+			fmt.Printf("%s = %v\n", name, names[name])
+		}
+	}
+
+	return nil
+}
+
+func listFuncs(interpreter *interpret.Interpreter, _ string) error {
+	names := interpreter.Names()
+	for _, name := range sortedNames(names) {
+		if isFunction(names[name]) {
+			fmt.Println(name)
+		}
+	}
+
+	return nil
+}
+
+func printTokens(_ *interpret.Interpreter, expr string) error {
+	lexer := lex.NewLexer(expr)
+	for {
+		lexeme, err := lexer.Next()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(lexeme)
+		if lexeme.Type == lex.EOF {
+			return nil
+		}
+	}
+}
+
+func printAST(_ *interpret.Interpreter, expr string) error {
+	tree, err := parse.NewParser(lex.NewLexer(expr)).Parse()
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range tree {
+		fmt.Print(ast.Dump(branch))
+	}
+
+	return nil
+}
+
+func reset(interpreter *interpret.Interpreter, _ string) error {
+	interpreter.Reset()
+	return nil
+}
+
+func cache(interpreter *interpret.Interpreter, action string) error {
+	if action != "clear" {
+		return fmt.Errorf("usage: :cache clear")
+	}
+
+	interpreter.ClearCache()
+
+	return nil
+}
+
+func load(interpreter *interpret.Interpreter, path string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("usage: :load <file>")
+	}
+
+	return runFile(interpreter, path)
+}
+
+func save(interpreter *interpret.Interpreter, path string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("usage: :save <file>")
+	}
+
+	return saveSession(interpreter, path)
+}
+
+func help(*interpret.Interpreter, string) error {
+	for _, cmd := range commands {
+		fmt.Printf("  %-16s %s\n", strings.TrimSpace(":"+cmd.name+" "+cmd.usage), cmd.help)
+	}
+
+	return nil
+}
+
+func sortedNames(names map[string]ast.Node) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func isFunction(value ast.Node) bool {
+	switch value.(type) {
+	case ast.Function, ast.Closure, ast.Form, ast.NamedFunction, ast.Impure:
+		return true
+	}
+
+	return false
+}
@@ -1,64 +1,102 @@
 package main
 
 import (
-	"bufio"
+	"calculator"
 	"calculator/backend/interpret"
 	"calculator/frontend/lex"
 	"calculator/frontend/parse"
 	"calculator/frontend/parse/ast"
+	"calculator/internal/lineedit"
+	"calculator/internal/lsp"
+	"calculator/internal/server"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 )
 
-func input(prompt string) string {
-	fmt.Print(prompt)
-	reader := bufio.NewReader(os.Stdin)
-	text, err := reader.ReadString('\n')
-	if err != nil {
-		panic(err)
-	}
+// sandboxLimits bound evaluations of code sent by clients of the servers
+var sandboxLimits = interpret.Limits{
+	MaxDepth:      1000,
+	MaxSteps:      10_000_000,
+	Timeout:       5 * time.Second,
+	MaxNumberBits: 4096,
+}
+
+// newInterpreter returns the interpreter of the shell, having the standard builtins
+func newInterpreter(symbolicMode bool) *interpret.Interpreter {
+	interpreter := interpret.NewInterpreter(calculator.Builtins())
+	interpreter.SetSymbolic(symbolicMode)
+
+	return interpreter
+}
 
-	return text
+// newSandbox returns the interpreter of clients of the servers, evaluating their
+// code within the limits
+func newSandbox(symbolicMode bool) *interpret.Interpreter {
+	interpreter := newInterpreter(symbolicMode)
+	interpreter.SetLimits(sandboxLimits)
+
+	return interpreter
 }
 
-func repl() error {
+func repl(interpreter *interpret.Interpreter) error {
 	const prompt = "> "
-	interpreter := interpret.NewInterpreter(map[string]ast.Node{
-		"x": ast.Integer(5),
-		"f": func(args ...ast.Node) (ast.Node, error) {
-			fmt.Println(args)
-			return ast.Integer(10), nil
-		},
-		"sum": func(args ...ast.Node) (ast.Node, error) {
-			var counter ast.Integer
-
-			for _, argNode := range args {
-				arg, ok := argNode.(ast.Integer)
-				if !ok {
-					return nil, fmt.Errorf("cannot use %v as integer", argNode)
-				}
-
-				counter += arg
-			}
-
-			return counter, nil
-		},
-	})
+	editor := lineedit.New(os.Stdin, os.Stdout, loadHistory())
 
 	for {
-		expr := input(prompt)
-		if err := calculate(interpreter, expr); err != nil {
+		expr, err := editor.ReadLine(prompt)
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, lineedit.ErrInterrupted):
+			continue
+		case err != nil:
+			return err
+		}
+
+		if isCommand(expr) {
+			err = runCommand(interpreter, expr)
+		} else {
+			err = calculate(interpreter, expr)
+		}
+
+		if err != nil {
 			fmt.Println("error:", err)
 		}
 	}
 }
 
-func calculate(interpreter interpret.Interpreter, expr string) error {
+// loadHistory loads the history file from the user's config dir. If it can't be
+// loaded, the history is kept in memory only
+func loadHistory() *lineedit.History {
+	path, err := lineedit.DefaultHistoryPath()
+	if err == nil {
+		var history *lineedit.History
+		if history, err = lineedit.LoadHistory(path); err == nil {
+			return history
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "history won't be saved:", err)
+
+	return nil
+}
+
+func calculate(interpreter *interpret.Interpreter, expr string) error {
 	tree, err := parse.NewParser(lex.NewLexer(expr)).Parse()
 	if err != nil {
 		return err
 	}
 
+	defer interpreter.Begin(context.Background())()
+
 	for _, branch := range tree {
 		result, err := interpreter.Evaluate(branch)
 		if err != nil {
@@ -71,7 +109,210 @@ func calculate(interpreter interpret.Interpreter, expr string) error {
 	return nil
 }
 
+// execute runs the script statement by statement, as it is being read. Unlike
+// calculate, results of definitions aren't printed. Errors are prefixed by the
+// position of the failed statement as line:char
+func execute(interpreter *interpret.Interpreter, script io.Reader) error {
+	defer interpreter.Begin(context.Background())()
+
+	parser := parse.NewParser(lex.NewReaderLexer(script))
+	for {
+		branch, err := parser.Next()
+		var syntaxErr *parse.Error
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.As(err, &syntaxErr):
+			return fmt.Errorf("%s: %w", syntaxErr.Pos, err)
+		case err != nil:
+			return err
+		}
+
+		result, err := interpreter.Evaluate(branch)
+		if err != nil {
+			positions := parser.Positions()
+			return fmt.Errorf("%s: %w", positions[len(positions)-1], err)
+		}
+
+		switch branch.(type) {
+		case ast.Def, ast.FDef, ast.Assign:
+		default:
+			fmt.Println(result)
+		}
+	}
+}
+
+// pipe runs the script from a pipe like execute, but lines starting with a colon
+// are meta-commands, like in the shell
+func pipe(interpreter *interpret.Interpreter, script io.Reader) error {
+	commands := newScriptCommands(interpreter, script)
+	if err := execute(interpreter, commands); err != nil {
+		return err
+	}
+
+	return commands.err
+}
+
+func runFile(interpreter *interpret.Interpreter, path string) error {
+	script, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer script.Close()
+
+	if err = execute(interpreter, script); err != nil {
+		return fmt.Errorf("%s:%w", path, err)
+	}
+
+	return nil
+}
+
+// loadSession restores definitions saved by saveSession. A missing file means
+// a new session
+func loadSession(interpreter *interpret.Interpreter, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return runFile(interpreter, path)
+}
+
+// saveSession writes the user definitions as a script. Builtins aren't saved
+func saveSession(interpreter *interpret.Interpreter, path string) error {
+	definitions, err := interpreter.Definitions()
+	if err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	for _, definition := range definitions {
+		writeDoc(&script, definition)
+		script.WriteString(ast.Format(definition))
+		script.WriteString("\n")
+	}
+
+	return os.WriteFile(path, []byte(script.String()), 0o644)
+}
+
+// writeDoc writes comments of the definition back, so they survive saving
+func writeDoc(script *strings.Builder, definition ast.Node) {
+	var doc string
+	switch def := definition.(type) {
+	case ast.Def:
+		doc = def.Doc
+	case ast.FDef:
+		doc = def.Doc
+	}
+
+	if len(doc) == 0 {
+		return
+	}
+
+	for _, line := range strings.Split(doc, "\n") {
+		script.WriteString(strings.TrimSpace("# " + line))
+		script.WriteString("\n")
+	}
+}
+
+func serve(args []string, newInterpreter func() *interpret.Interpreter) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	idle := flags.Duration("idle", 30*time.Minute, "remove sessions staying idle for longer")
+	timeout := flags.Duration("timeout", sandboxLimits.Timeout, "abort evaluations taking longer")
+	sessions := flags.Int("sessions", 1000, "maximum number of sessions existing at once, 0 for no limit")
+	_ = flags.Parse(args)
+
+	if *idle < 0 {
+		return fmt.Errorf("idle timeout must not be negative, got %s", *idle)
+	}
+
+	if *sessions < 0 {
+		return fmt.Errorf("maximum number of sessions must not be negative, got %d", *sessions)
+	}
+
+	limits := sandboxLimits
+	limits.Timeout = *timeout
+	srv := server.New(func() *interpret.Interpreter {
+		interpreter := newInterpreter()
+		interpreter.SetLimits(limits)
+
+		return interpreter
+	}, *idle, *sessions)
+	defer srv.Close()
+
+	fmt.Fprintln(os.Stderr, "listening on", *addr)
+
+	return http.ListenAndServe(*addr, srv)
+}
+
+// serveLSP runs the language server over stdio. Builtins printing to stdout
+// would break the protocol, so their output is redirected to stderr
+func serveLSP(newInterpreter func() *interpret.Interpreter) error {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	return lsp.New(newInterpreter).Serve(os.Stdin, stdout)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage:
+  calculator [flags]             run the interactive shell, or the script from stdin
+  calculator [flags] run <file>  run the script
+  calculator [flags] -e <expr>   evaluate the expression
+  calculator [flags] serve [-addr <addr>] [-idle <timeout>] [-timeout <timeout>] [-sessions <n>]
+                                 serve the HTTP JSON API
+  calculator [flags] lsp         run the language server over stdio
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
 func main() {
-	//fmt.Println(calculate(interpret.NewInterpreter(nil), "-(2+2)*x"))
-	fmt.Println("repl:", repl())
+	symbolicMode := flag.Bool("symbolic", false, "keep unbound names as symbols")
+	expr := flag.String("e", "", "evaluate the expression and exit")
+	session := flag.String("session", "", "restore definitions from the file, and save them back on exit")
+	flag.Usage = usage
+	flag.Parse()
+
+	interpreter := newInterpreter(*symbolicMode)
+	if len(*session) > 0 {
+		if err := loadSession(interpreter, *session); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+
+	var err error
+	switch {
+	case len(*expr) > 0:
+		err = execute(interpreter, strings.NewReader(*expr))
+	case flag.Arg(0) == "run" && flag.NArg() == 2:
+		err = runFile(interpreter, flag.Arg(1))
+	case flag.Arg(0) == "serve":
+		err = serve(flag.Args()[1:], func() *interpret.Interpreter {
+			return newSandbox(*symbolicMode)
+		})
+	case flag.Arg(0) == "lsp" && flag.NArg() == 1:
+		err = serveLSP(func() *interpret.Interpreter {
+			return newSandbox(*symbolicMode)
+		})
+	case flag.NArg() > 0:
+		usage()
+		os.Exit(2)
+	case !lineedit.IsTerminal(os.Stdin):
+		err = pipe(interpreter, os.Stdin)
+	default:
+		err = repl(interpreter)
+	}
+
+	if err == nil && len(*session) > 0 {
+		err = saveSession(interpreter, *session)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
 }
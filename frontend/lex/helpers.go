@@ -1,19 +1,45 @@
 package lex
 
-func isInt(b byte) bool {
-	return b >= '0' && b <= '9'
+import "unicode"
+
+func isInt(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isString(r rune) bool {
+	return r == '"'
+}
+
+func isIdent(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
 }
 
-func isString(b byte) bool {
-	return b == '"'
+// isIdentTail also accepts combining marks, so letters with diacritics written
+// in the decomposed form stay a single identifier
+func isIdentTail(r rune) bool {
+	return isIdent(r) || isInt(r) || unicode.Is(unicode.Mn, r)
 }
 
-func isIdent(b byte) bool {
-	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b == '_'
+// superscripts are digits written as superscripts, ordered by their value
+const superscripts = "⁰¹²³⁴⁵⁶⁷⁸⁹"
+
+func isSuperscript(r rune) bool {
+	return superscriptDigit(r) != 0
 }
 
-func isIdentTail(b byte) bool {
-	return isIdent(b) || isInt(b)
+// superscriptDigit returns the ASCII digit, written by the superscript rune, or
+// zero if the rune isn't a superscript digit
+func superscriptDigit(r rune) byte {
+	var digit byte = '0'
+	for _, superscript := range superscripts {
+		if r == superscript {
+			return digit
+		}
+
+		digit++
+	}
+
+	return 0
 }
 
 func isKeyword(str string) bool {
@@ -1,7 +1,10 @@
 package lex
 
 const (
-	Fn = "fn"
+	Fn    = "fn"
+	Const = "const"
+	If    = "if"
+	Memo  = "memo"
 )
 
-var Keywords = []string{Fn}
+var Keywords = []string{Fn, Const, If, Memo}
@@ -1,25 +1,59 @@
 package lex
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"unicode/utf8"
 )
 
 type Lexer struct {
+	// input is the part of the input not lexed yet. When reading from a reader,
+	// it holds the rest of the current line only
 	input          string
+	reader         *bufio.Reader
+	readErr        error
 	previous       Lexeme
 	returnPrevious bool
-	pos            Position
+	// pending are lexemes implied by the input, emitted before lexing further.
+	// Like the implicit multiplication between a number and an identifier in 2x,
+	// or the exponent in x²
+	pending []Lexeme
+	pos     Position
+	// start is the position of the previous lexeme
+	start Position
+	// trivia are comments met since the previous lexeme, and leading are the ones
+	// preceding it
+	trivia, leading []string
+	// breaks is the number of line breaks since the previous lexeme or comment
+	breaks int
+	// brackets are parenthesis and braces open, the innermost last. Line breaks
+	// inside parenthesis don't terminate statements, unlike inside braces
+	brackets []LexemeType
 }
 
 func NewLexer(input string) *Lexer {
 	return &Lexer{
-		input:    input,
+		input:    skipShebang(input),
 		previous: Lexeme{Type: Untyped},
 	}
 }
 
+// NewReaderLexer returns a lexer reading the input as it goes, a line at a time.
+// Lexemes never span lines, so they are the same as of the string lexer
+func NewReaderLexer(r io.Reader) *Lexer {
+	l := &Lexer{
+		reader:   bufio.NewReader(r),
+		previous: Lexeme{Type: Untyped},
+	}
+	l.refill()
+	l.input = skipShebang(l.input)
+
+	return l
+}
+
 func (l *Lexer) Next() (Lexeme, error) {
 	if l.returnPrevious {
 		l.returnPrevious = false
@@ -27,7 +61,25 @@ func (l *Lexer) Next() (Lexeme, error) {
 		return l.previous, nil
 	}
 
+	if len(l.pending) > 0 {
+		lexeme := l.pending[0]
+		l.pending = l.pending[1:]
+		l.start = l.pos
+		l.leading = nil
+		return l.save(lexeme), nil
+	}
+
 	l.skipWhitespaces()
+	l.start = l.pos
+	l.leading, l.trivia, l.breaks = l.trivia, nil, 0
+	if l.readErr != nil {
+		return Lexeme{}, l.readErr
+	}
+
+	if strings.HasPrefix(l.input, "/*") {
+		l.after(len(l.input))
+		return l.save(Lexeme{}), errors.New("unterminated block comment")
+	}
 
 	switch typ := l.guessLexemeType(); typ {
 	case EOF:
@@ -36,7 +88,15 @@ func (l *Lexer) Next() (Lexeme, error) {
 		return l.save(Lexeme{}), errors.New("unrecognized lexeme: " + untilSpace(l.input))
 	case Number:
 		value, err := l.parseNumber()
+		if r, _ := utf8.DecodeRuneInString(l.input); isIdent(r) {
+			l.pending = append(l.pending, Lexeme{OpStar, ""})
+		}
+
 		return l.save(Lexeme{Number, value}), err
+	case superscript:
+		// x² is lexed just like x^2
+		l.pending = append(l.pending, Lexeme{Number, l.parseSuperscript()})
+		return l.save(Lexeme{OpCaret, ""}), nil
 	case Id:
 		value, err := l.parseId()
 
@@ -48,17 +108,51 @@ func (l *Lexer) Next() (Lexeme, error) {
 	case symbol:
 		lexeme, err := l.parseOperator()
 		return l.save(lexeme), err
-	case LParen:
-		return l.save(Lexeme{LParen, l.after(1)}), nil
-	case RParen:
-		return l.save(Lexeme{RParen, l.after(1)}), nil
+	case LParen, LBrace:
+		l.brackets = append(l.brackets, typ)
+		return l.save(Lexeme{typ, l.after(1)}), nil
+	case RParen, RBrace:
+		if len(l.brackets) > 0 {
+			l.brackets = l.brackets[:len(l.brackets)-1]
+		}
+
+		return l.save(Lexeme{typ, l.after(1)}), nil
+	case ChSemicolon:
+		return l.save(Lexeme{ChSemicolon, l.after(1)}), nil
+	case Newline:
+		l.after(1)
+		l.pos.Line++
+		l.pos.Char = 0
+		return l.save(Lexeme{Type: Newline}), nil
 	default:
 		panic("BUG: guessLexemeType() returned unknown lexeme type")
 	}
 }
 
+// EOF reports whether there are no lexemes left, including the one put back
 func (l *Lexer) EOF() bool {
-	return len(l.input) == 0
+	switch {
+	case l.returnPrevious:
+		return l.previous.Type == EOF
+	case len(l.pending) > 0:
+		return false
+	}
+
+	l.skipWhitespaces()
+
+	return len(l.input) == 0 && l.readErr == nil
+}
+
+// Position returns where the lexeme, returned by the last Next call, starts
+func (l *Lexer) Position() Position {
+	return l.start
+}
+
+// Trivia returns comments preceding the lexeme, returned by the last Next call,
+// with comment markers and surrounding spaces stripped. Comments following a
+// lexeme on the same line, and ones separated by a blank line, aren't included
+func (l *Lexer) Trivia() []string {
+	return l.leading
 }
 
 func (l *Lexer) Back() {
@@ -70,95 +164,283 @@ func (l *Lexer) save(lexeme Lexeme) Lexeme {
 	return lexeme
 }
 
+// skipWhitespaces skips spaces, line breaks and comments. An unterminated block
+// comment is left in the input
 func (l *Lexer) skipWhitespaces() {
-	for i, char := range l.input {
-		switch char {
-		case ' ', '\t', '\r':
-			l.pos.Char++
-		case '\n':
-			l.pos.Char = 0
-			l.pos.Line++
+	for {
+		l.skipBlanks()
+
+		switch {
+		case strings.HasPrefix(l.input, "#"):
+			l.lineComment(1)
+		case strings.HasPrefix(l.input, "//"):
+			l.lineComment(2)
+		case strings.HasPrefix(l.input, "/*"):
+			if !l.blockComment() {
+				return
+			}
 		default:
-			l.input = l.input[i:]
 			return
 		}
 	}
+}
+
+func (l *Lexer) skipBlanks() {
+	for {
+		for i, char := range l.input {
+			switch char {
+			case ' ', '\t', '\r':
+				l.pos.Char++
+			case '\n':
+				if l.terminates() {
+					l.input = l.input[i:]
+					return
+				}
+
+				l.pos.Char = 0
+				l.pos.Line++
+				l.breaks++
+				if l.breaks > 1 {
+					l.trivia = nil
+				}
+			default:
+				l.input = l.input[i:]
+				return
+			}
+		}
 
-	l.input = ""
+		l.input = ""
+		if !l.refill() {
+			return
+		}
+	}
 }
 
-func (l *Lexer) parseNumber() (string, error) {
-	for i := 0; i < len(l.input); i++ {
-		if !isInt(l.input[i]) {
-			return l.after(i), nil
+// terminates reports whether a line break at this point terminates the statement,
+// so must be lexed as Newline instead of being skipped
+func (l *Lexer) terminates() bool {
+	if len(l.brackets) > 0 && l.brackets[len(l.brackets)-1] == LParen {
+		return false
+	}
+
+	return l.previous.Type.EndsExpression()
+}
+
+// lineComment skips the comment up to the line break, which is left in the input
+func (l *Lexer) lineComment(marker int) {
+	end := strings.IndexByte(l.input, '\n')
+	if end < 0 {
+		end = len(l.input)
+	}
+
+	l.comment(l.after(end)[marker:])
+}
+
+// blockComment skips the comment, reading the following lines until it's closed.
+// It reports whether the comment was closed
+func (l *Lexer) blockComment() bool {
+	end := strings.Index(l.input[2:], "*/")
+	for end < 0 {
+		// the closing marker may be split between the lines, but mustn't overlap
+		// the opening one
+		from := len(l.input) - 1
+		if from < 2 {
+			from = 2
+		}
+
+		line := l.readLine()
+		if len(line) == 0 {
+			return false
+		}
+
+		l.input += line
+		if end = strings.Index(l.input[from:], "*/"); end >= 0 {
+			end += from - 2
 		}
 	}
 
-	return l.after(len(l.input)), nil
+	text := l.input[:end+4]
+	l.input = l.input[end+4:]
+	if lines := strings.Count(text, "\n"); lines > 0 {
+		l.pos.Line += lines
+		l.pos.Char = utf8.RuneCountInString(text[strings.LastIndexByte(text, '\n')+1:])
+	} else {
+		l.pos.Char += utf8.RuneCountInString(text)
+	}
+
+	l.comment(text[2 : end+2])
+
+	return true
+}
+
+// comment records the comment as trivia, unless it follows a lexeme on the same
+// line, so belongs to it rather than to the next one
+func (l *Lexer) comment(text string) {
+	if l.breaks == 0 && l.previous.Type != Untyped && l.previous.Type != Newline {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	l.trivia = append(l.trivia, strings.Join(lines, "\n"))
+	l.breaks = 0
+}
+
+// refill reads the next line, once the input is over. It reports whether anything
+// was read
+func (l *Lexer) refill() bool {
+	if len(l.input) > 0 {
+		return false
+	}
+
+	l.input = l.readLine()
+
+	return len(l.input) > 0
+}
+
+// readLine returns the next line of the reader, or nothing if there is no reader
+// or it's over. Failures of reading, apart from io.EOF, are returned by the next
+// Next
+func (l *Lexer) readLine() string {
+	if l.reader == nil || l.readErr != nil {
+		return ""
+	}
+
+	line, err := l.reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		l.readErr = err
+	}
+
+	return line
+}
+
+func (l *Lexer) parseNumber() (string, error) {
+	n, err := scanNumber(l.input)
+	literal := l.after(n)
+	if err != nil {
+		return literal, fmt.Errorf("malformed number %s: %w", literal, err)
+	}
+
+	return literal, nil
 }
 
 func (l *Lexer) parseId() (string, error) {
-	for i := 0; i < len(l.input); i++ {
-		if !isIdentTail(l.input[i]) {
-			return l.after(i), nil
+	return l.after(l.span(isIdentTail)), nil
+}
+
+// parseSuperscript returns the number written in superscript digits as a usual one
+func (l *Lexer) parseSuperscript() string {
+	var number strings.Builder
+	for _, r := range l.after(l.span(isSuperscript)) {
+		number.WriteByte(superscriptDigit(r))
+	}
+
+	return number.String()
+}
+
+// span returns the length in bytes of the input prefix, consisting of runes
+// matching the predicate
+func (l *Lexer) span(predicate func(rune) bool) int {
+	for i, r := range l.input {
+		if !predicate(r) {
+			return i
 		}
 	}
 
-	return l.after(len(l.input)), nil
+	return len(l.input)
 }
 
 func (l *Lexer) parseOperator() (Lexeme, error) {
-	for i := 1; i < len(l.input); i++ {
-		if !isSymbolPrefix(l.input[:i+1]) {
-			sym := l.after(i)
-			symType := symbolType(sym)
-			if symType == Untyped {
-				return Lexeme{}, fmt.Errorf("unknown operator: %s", sym)
-			}
+	// the longest symbol matching the input is taken
+	end := 0
+	for end < len(l.input) {
+		_, size := utf8.DecodeRuneInString(l.input[end:])
+		if !isSymbolPrefix(l.input[:end+size]) {
+			break
+		}
 
-			if l.previous.Type.FollowingSymCanBeUnary() {
-				unType := symType.AsUnary()
-				if unType == Untyped {
-					return Lexeme{}, fmt.Errorf("unknown unary: %s", sym)
-				}
+		end += size
+	}
 
-				return Lexeme{unType, sym}, nil
-			}
+	if end == len(l.input) {
+		return Lexeme{}, fmt.Errorf("incomplete expression: no right operand")
+	}
+
+	sym := l.after(end)
+	symType := symbolType(sym)
+	if symType == Untyped {
+		return Lexeme{}, fmt.Errorf("unknown operator: %s", sym)
+	}
 
-			return Lexeme{symType, sym}, nil
+	if l.previous.Type.FollowingSymCanBeUnary() {
+		unType := symType.AsUnary()
+		if unType == Untyped {
+			return Lexeme{}, fmt.Errorf("unknown unary: %s", sym)
 		}
+
+		return Lexeme{unType, sym}, nil
 	}
 
-	return Lexeme{}, fmt.Errorf("incomplete expression: no right operand")
+	return Lexeme{symType, sym}, nil
 }
 
 func (l *Lexer) guessLexemeType() LexemeType {
+	r, size := utf8.DecodeRuneInString(l.input)
+
 	switch {
 	case len(l.input) == 0:
 		return EOF
-	case isInt(l.input[0]):
+	case isInt(r):
 		return Number
-	case isIdent(l.input[0]):
+	case isIdent(r):
 		return Id
-	case isSymbolPrefix(l.input[:1]):
+	case isSuperscript(r):
+		return superscript
+	case isSymbolPrefix(l.input[:size]):
 		return symbol
-	case l.input[0] == '(':
+	case r == '(':
 		return LParen
-	case l.input[0] == ')':
+	case r == ')':
 		return RParen
+	case r == '{':
+		return LBrace
+	case r == '}':
+		return RBrace
+	case r == ';':
+		return ChSemicolon
+	case r == '\n':
+		return Newline
 	}
 
 	return Untyped
 }
 
+// after consumes n bytes of the input. The position is advanced by runes
 func (l *Lexer) after(n int) string {
-	l.pos.Char += n
+	l.pos.Char += utf8.RuneCountInString(l.input[:n])
 	after := l.input[:n]
 	l.input = l.input[n:]
 
 	return after
 }
 
+// skipShebang drops the interpreter directive of an executable script. The line
+// break is kept, so lines are still counted correctly
+func skipShebang(input string) string {
+	if !strings.HasPrefix(input, "#!") {
+		return input
+	}
+
+	if i := strings.IndexByte(input, '\n'); i >= 0 {
+		return input[i:]
+	}
+
+	return ""
+}
+
 func untilSpace(str string) string {
 	before, _, _ := strings.Cut(str, " ")
 	return before
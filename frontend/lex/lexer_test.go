@@ -1,8 +1,12 @@
 package lex
 
 import (
+	"errors"
 	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestLexer(t *testing.T) {
@@ -54,6 +58,13 @@ func TestLexer(t *testing.T) {
 			Lexeme{Id, "b"})
 	})
 
+	t.Run("unary after comma", func(t *testing.T) {
+		testLexer(
+			t, "a,-b",
+			Lexeme{Id, "a"}, Lexeme{ChComma, ","},
+			Lexeme{UnMinus, "-"}, Lexeme{Id, "b"})
+	})
+
 	t.Run("fn keyword", func(t *testing.T) {
 		testLexer(
 			t, "fn f",
@@ -61,6 +72,149 @@ func TestLexer(t *testing.T) {
 		)
 	})
 
+	t.Run("coefficient", func(t *testing.T) {
+		testLexer(
+			t, "3x-2 y",
+			Lexeme{Number, "3"}, Lexeme{OpStar, ""}, Lexeme{Id, "x"},
+			Lexeme{OpMinus, "-"}, Lexeme{Number, "2"}, Lexeme{Id, "y"},
+		)
+	})
+
+	t.Run("shebang", func(t *testing.T) {
+		testLexer(
+			t, "#!/usr/bin/env -S calculator run\n1",
+			Lexeme{Number, "1"},
+		)
+	})
+
+	t.Run("unicode identifiers", func(t *testing.T) {
+		testLexer(
+			t, "π+résumé*Δx_2",
+			Lexeme{Id, "π"}, Lexeme{OpPlus, "+"}, Lexeme{Id, "résumé"},
+			Lexeme{OpStar, "*"}, Lexeme{Id, "Δx_2"},
+		)
+	})
+
+	t.Run("math symbols", func(t *testing.T) {
+		testLexer(
+			t, "2θ×3÷−√x−1",
+			Lexeme{Number, "2"}, Lexeme{OpStar, ""}, Lexeme{Id, "θ"},
+			Lexeme{OpStar, "×"}, Lexeme{Number, "3"}, Lexeme{OpSlash, "÷"},
+			Lexeme{UnMinus, "−"}, Lexeme{UnRoot, "√"}, Lexeme{Id, "x"},
+			Lexeme{OpMinus, "−"}, Lexeme{Number, "1"},
+		)
+	})
+
+	t.Run("superscripts", func(t *testing.T) {
+		testLexer(
+			t, "x²+y¹⁰",
+			Lexeme{Id, "x"}, Lexeme{OpCaret, ""}, Lexeme{Number, "2"},
+			Lexeme{OpPlus, "+"},
+			Lexeme{Id, "y"}, Lexeme{OpCaret, ""}, Lexeme{Number, "10"},
+		)
+	})
+
+	t.Run("positions count runes", func(t *testing.T) {
+		lexer := NewLexer("π² × θ\n√é")
+		var positions []Position
+		for !lexer.EOF() {
+			_, err := lexer.Next()
+			require.NoError(t, err)
+			positions = append(positions, lexer.Position())
+		}
+
+		require.Equal(t, []Position{
+			{Line: 0, Char: 0}, {Line: 0, Char: 1}, {Line: 0, Char: 2}, {Line: 0, Char: 3},
+			{Line: 0, Char: 5}, {Line: 0, Char: 6}, {Line: 1, Char: 0}, {Line: 1, Char: 1},
+		}, positions)
+	})
+
+	t.Run("number literals", func(t *testing.T) {
+		testLexer(
+			t, "0x1F+0o17*0b1011-1_000_000/6.02e23+1E-9+2eps",
+			Lexeme{Number, "0x1F"}, Lexeme{OpPlus, "+"}, Lexeme{Number, "0o17"},
+			Lexeme{OpStar, "*"}, Lexeme{Number, "0b1011"}, Lexeme{OpMinus, "-"},
+			Lexeme{Number, "1_000_000"}, Lexeme{OpSlash, "/"}, Lexeme{Number, "6.02e23"},
+			Lexeme{OpPlus, "+"}, Lexeme{Number, "1E-9"}, Lexeme{OpPlus, "+"},
+			Lexeme{Number, "2"}, Lexeme{OpStar, ""}, Lexeme{Id, "eps"},
+		)
+	})
+
+	t.Run("malformed number literals", func(t *testing.T) {
+		for code, want := range map[string]string{
+			"0x":      "malformed number 0x: no digits after 0x",
+			"0x_":     "malformed number 0x_: no digits after 0x",
+			"1__0":    "malformed number 1__0: consecutive underscores",
+			"1_":      "malformed number 1_: trailing underscore",
+			"1.5_":    "malformed number 1.5_: trailing underscore",
+			"0b102":   "malformed number 0b102: invalid digit '2' for base 2",
+			"0o19+1":  "malformed number 0o19: invalid digit '9' for base 8",
+			"1.":      "malformed number 1.: missing digits after decimal point",
+			"1.+2":    "malformed number 1.: missing digits after decimal point",
+			"1e":      "malformed number 1e: missing exponent digits",
+			"1e+":     "malformed number 1e+: missing exponent digits",
+			"1.5E-)":  "malformed number 1.5E-: missing exponent digits",
+			"0x1.8p1": "malformed number 0x1.8p1: fractional part isn't allowed in base 16",
+			"0b1.1":   "malformed number 0b1.1: fractional part isn't allowed in base 2",
+		} {
+			lexer := NewLexer(code)
+			_, err := lexer.Next()
+			require.EqualError(t, err, want, code)
+		}
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		testLexer(
+			t, "# first\na // second\n/* multi\nline */ + /**/ b/**/c # the end",
+			Lexeme{Id, "a"}, Lexeme{Type: Newline}, Lexeme{UnPlus, "+"}, Lexeme{Id, "b"}, Lexeme{Id, "c"},
+		)
+	})
+
+	t.Run("trivia", func(t *testing.T) {
+		lexer := NewLexer("# about a\n// more\na -> 1 # one\n\n# detached\n\n/* b */ b")
+		var trivia [][]string
+		var positions []Position
+		for !lexer.EOF() {
+			_, err := lexer.Next()
+			require.NoError(t, err)
+			trivia = append(trivia, lexer.Trivia())
+			positions = append(positions, lexer.Position())
+		}
+
+		require.Equal(t, [][]string{{"about a", "more"}, nil, nil, nil, {"b"}}, trivia)
+		require.Equal(t, []Position{
+			{Line: 2, Char: 0}, {Line: 2, Char: 2}, {Line: 2, Char: 5}, {Line: 2, Char: 12}, {Line: 6, Char: 8},
+		}, positions)
+	})
+
+	t.Run("unterminated block comment", func(t *testing.T) {
+		lexer := NewLexer("1 /* never\nclosed")
+		_, err := lexer.Next()
+		require.NoError(t, err)
+		_, err = lexer.Next()
+		require.EqualError(t, err, "unterminated block comment")
+	})
+
+	t.Run("statement separators", func(t *testing.T) {
+		testLexer(
+			t, "a;b\nf(1,\n2) +\n3\n\nc",
+			Lexeme{Id, "a"}, Lexeme{ChSemicolon, ";"}, Lexeme{Id, "b"}, Lexeme{Type: Newline},
+			Lexeme{Id, "f"}, Lexeme{LParen, "("}, Lexeme{Number, "1"}, Lexeme{ChComma, ","},
+			Lexeme{Number, "2"}, Lexeme{RParen, ")"}, Lexeme{OpPlus, "+"}, Lexeme{Number, "3"},
+			Lexeme{Type: Newline}, Lexeme{Id, "c"},
+		)
+	})
+
+	t.Run("comparisons", func(t *testing.T) {
+		testLexer(
+			t, "a<=b==c!=-1>=d<-e",
+			Lexeme{Id, "a"}, Lexeme{OpLessEqual, "<="}, Lexeme{Id, "b"},
+			Lexeme{OpEquals, "=="}, Lexeme{Id, "c"}, Lexeme{OpNotEquals, "!="},
+			Lexeme{UnMinus, "-"}, Lexeme{Number, "1"}, Lexeme{OpGreaterEqual, ">="},
+			Lexeme{Id, "d"}, Lexeme{OpLess, "<"}, Lexeme{UnMinus, "-"}, Lexeme{Id, "e"},
+		)
+	})
+
 	t.Run("2-complement operator", func(t *testing.T) {
 		testLexer(
 			t, "a->b",
@@ -82,3 +236,42 @@ func testLexer(t *testing.T, code string, want ...Lexeme) {
 	require.NoError(t, err)
 	require.Equal(t, Lexeme{Type: EOF}, lexeme)
 }
+
+func TestReaderLexer(t *testing.T) {
+	const code = "#!/usr/bin/env calculator\nf(x, y) -> 2x^y // power\n\n  a -> -f(1, /* split\n*\n*/ 2)\r\nsolve(a = 3, x) /* *\n/ */"
+
+	t.Run("same as string lexer", func(t *testing.T) {
+		want := NewLexer(code)
+		got := NewReaderLexer(iotest.OneByteReader(strings.NewReader(code)))
+
+		for {
+			wantLexeme, wantErr := want.Next()
+			lexeme, err := got.Next()
+			require.Equal(t, wantErr, err)
+			require.Equal(t, wantLexeme, lexeme)
+			require.Equal(t, want.Position(), got.Position())
+			require.Equal(t, want.Trivia(), got.Trivia())
+			require.Equal(t, want.EOF(), got.EOF())
+
+			if lexeme.Type == EOF {
+				break
+			}
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		failure := errors.New("failure")
+		lexer := NewReaderLexer(io.MultiReader(strings.NewReader("1\n"), iotest.ErrReader(failure)))
+		lexeme, err := lexer.Next()
+		require.NoError(t, err)
+		require.Equal(t, Lexeme{Number, "1"}, lexeme)
+
+		lexeme, err = lexer.Next()
+		require.NoError(t, err)
+		require.Equal(t, Lexeme{Type: Newline}, lexeme)
+
+		require.False(t, lexer.EOF())
+		_, err = lexer.Next()
+		require.ErrorIs(t, err, failure)
+	})
+}
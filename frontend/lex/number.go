@@ -0,0 +1,171 @@
+package lex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// scanNumber returns the length of the numeric literal the input starts with.
+// Literals may be prefixed by the base (0x, 0o or 0b), have digits separated by
+// underscores, and decimal ones may have a fractional part and an exponent, like
+// 6.02e23. The length is returned along with the error for malformed literals, so
+// they can be consumed as a whole. A point followed by another one isn't a part
+// of the literal, so 1... is spread
+func scanNumber(input string) (int, error) {
+	base, i := 10, 0
+	if len(input) > 1 && input[0] == '0' {
+		switch input[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+
+		if base != 10 {
+			i = 2
+		}
+	}
+
+	end, err := scanDigits(input, i, base)
+	if base != 10 {
+		if strings.Trim(input[i:end], "_") == "" {
+			err = fmt.Errorf("no digits after %s", input[:2])
+		}
+
+		if isPoint(input[end:]) {
+			end = skipFraction(input, end, base)
+			if err == nil {
+				err = fmt.Errorf("fractional part isn't allowed in base %d", base)
+			}
+		}
+
+		return end, err
+	}
+
+	if isPoint(input[end:]) {
+		if end+1 == len(input) || !isInt(rune(input[end+1])) {
+			end++
+			if err == nil {
+				err = errors.New("missing digits after decimal point")
+			}
+		} else {
+			var fracErr error
+			if end, fracErr = scanDigits(input, end+1, base); err == nil {
+				err = fracErr
+			}
+		}
+	}
+
+	exp, expErr := exponentStart(input[end:])
+	if err == nil {
+		err = expErr
+	}
+
+	if exp > 0 {
+		var digitsErr error
+		if end, digitsErr = scanDigits(input, end+exp, base); err == nil {
+			err = digitsErr
+		}
+	}
+
+	return end, err
+}
+
+// isPoint reports whether the input starts with the decimal point, rather than
+// with the spread operator
+func isPoint(input string) bool {
+	return strings.HasPrefix(input, ".") && !strings.HasPrefix(input, "..")
+}
+
+// skipFraction returns where the fractional part of the non-decimal literal, like
+// .8p1 of 0x1.8p1, ends. Such literals aren't supported, but are consumed as a
+// whole, so they are reported once
+func skipFraction(input string, i, base int) int {
+	i, _ = scanDigits(input, i+1, base)
+	if base == 16 && i < len(input) && (input[i] == 'p' || input[i] == 'P') {
+		i++
+		if i < len(input) && (input[i] == '+' || input[i] == '-') {
+			i++
+		}
+
+		i, _ = scanDigits(input, i, 10)
+	}
+
+	return i
+}
+
+// scanDigits returns where the run of digits and underscores, starting at the
+// index, ends. Decimal digits not valid for the base are consumed, but result
+// in the error
+func scanDigits(input string, i, base int) (int, error) {
+	var err error
+	start := i
+
+	for ; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '_':
+			if i > start && input[i-1] == '_' && err == nil {
+				err = errors.New("consecutive underscores")
+			}
+		case digitValue(c) < base:
+		case isInt(rune(c)):
+			if err == nil {
+				err = fmt.Errorf("invalid digit %q for base %d", c, base)
+			}
+		default:
+			return i, withTrailingUnderscore(input, start, i, err)
+		}
+	}
+
+	return i, withTrailingUnderscore(input, start, i, err)
+}
+
+func withTrailingUnderscore(input string, start, end int, err error) error {
+	if err == nil && end > start && input[end-1] == '_' {
+		return errors.New("trailing underscore")
+	}
+
+	return err
+}
+
+// exponentStart returns the length of the exponent marker with its sign, if the
+// input starts with an exponent, like e-9. The marker followed by letters is
+// the identifier instead, so zero is returned and 2eps stays the coefficient
+// followed by it. The marker left without digits, like in 2e or 2e+, is an error
+func exponentStart(input string) (int, error) {
+	if len(input) == 0 || (input[0] != 'e' && input[0] != 'E') {
+		return 0, nil
+	}
+
+	n := 1
+	if next, _ := utf8.DecodeRuneInString(input[1:]); next == '+' || next == '-' {
+		n = 2
+	} else if !isInt(next) && (isIdentTail(next) || isSuperscript(next)) {
+		return 0, nil
+	}
+
+	if n < len(input) && isInt(rune(input[n])) {
+		return n, nil
+	}
+
+	return n, errors.New("missing exponent digits")
+}
+
+// digitValue returns the value of the hexadecimal digit, or 16 if it isn't one
+func digitValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+
+	return 16
+}
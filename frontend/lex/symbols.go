@@ -3,8 +3,12 @@ package lex
 import "strings"
 
 var (
-	allSymbols   = []string{plus, minus, star, slash, caret, comma, equal, flow}
-	unarySymbols = []string{plus, minus}
+	allSymbols = []string{
+		plus, minus, star, slash, caret, comma, equal, flow, assign, ellipsis,
+		less, greater, lessEqual, greaterEqual, equals, notEquals,
+		times, divide, minusSign, root,
+	}
+	unarySymbols = []string{plus, minus, minusSign, root}
 )
 
 const (
@@ -16,18 +20,38 @@ const (
 	comma = ","
 	equal = "="
 	flow  = "->"
+	// assign is the assignment to an existing name, unlike the flow defining one
+	assign = ":="
+	// ellipsis marks the rest parameter of a function, and spreads a list into
+	// arguments of a call
+	ellipsis = "..."
+
+	less         = "<"
+	greater      = ">"
+	lessEqual    = "<="
+	greaterEqual = ">="
+	equals       = "=="
+	notEquals    = "!="
+
+	// aliases of the operators above, as written in math
+	times     = "×"
+	divide    = "÷"
+	minusSign = "−"
+	root      = "√"
 )
 
 func symbolType(o string) LexemeType {
 	switch o {
 	case plus:
 		return OpPlus
-	case minus:
+	case minus, minusSign:
 		return OpMinus
-	case star:
+	case star, times:
 		return OpStar
-	case slash:
+	case slash, divide:
 		return OpSlash
+	case root:
+		return UnRoot
 	case caret:
 		return OpCaret
 	case comma:
@@ -36,6 +60,22 @@ func symbolType(o string) LexemeType {
 		return ChEqual
 	case flow:
 		return ChFlow
+	case assign:
+		return ChAssign
+	case ellipsis:
+		return ChEllipsis
+	case less:
+		return OpLess
+	case greater:
+		return OpGreater
+	case lessEqual:
+		return OpLessEqual
+	case greaterEqual:
+		return OpGreaterEqual
+	case equals:
+		return OpEquals
+	case notEquals:
+		return OpNotEquals
 	}
 
 	return Untyped
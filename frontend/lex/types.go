@@ -9,20 +9,38 @@ const (
 	EOF     LexemeType = "EOF"
 	Number  LexemeType = "NUMBER"
 	symbol  LexemeType = "SYMBOL"
-	OpPlus  LexemeType = "OP_PLUS"
-	OpMinus LexemeType = "OP_MINUS"
-	OpStar  LexemeType = "OP_STAR"
-	OpSlash LexemeType = "OP_SLASH"
-	OpCaret LexemeType = "OP_CARET"
-	UnPlus  LexemeType = "UN_PLUS"
-	UnMinus LexemeType = "UN_MINUS"
-	ChComma LexemeType = "CH_COMMA"
-	ChEqual LexemeType = "CH_EQUAL"
-	ChFlow  LexemeType = "CH_FLOW"
-	Id      LexemeType = "ID"
-	Keyword LexemeType = "KEYWORD"
-	LParen  LexemeType = "LPAREN"
-	RParen  LexemeType = "RPAREN"
+	// superscript is a power written as superscript digits, like in x²
+	superscript LexemeType = "SUPERSCRIPT"
+	OpPlus      LexemeType = "OP_PLUS"
+	OpMinus     LexemeType = "OP_MINUS"
+	OpStar      LexemeType = "OP_STAR"
+	OpSlash     LexemeType = "OP_SLASH"
+	OpCaret     LexemeType = "OP_CARET"
+	// comparisons result in 1 if hold, and 0 otherwise
+	OpLess         LexemeType = "OP_LESS"
+	OpGreater      LexemeType = "OP_GREATER"
+	OpLessEqual    LexemeType = "OP_LESS_EQUAL"
+	OpGreaterEqual LexemeType = "OP_GREATER_EQUAL"
+	OpEquals       LexemeType = "OP_EQUALS"
+	OpNotEquals    LexemeType = "OP_NOT_EQUALS"
+	UnPlus         LexemeType = "UN_PLUS"
+	UnMinus        LexemeType = "UN_MINUS"
+	// UnRoot is the square root
+	UnRoot     LexemeType = "UN_ROOT"
+	ChComma    LexemeType = "CH_COMMA"
+	ChEqual    LexemeType = "CH_EQUAL"
+	ChFlow     LexemeType = "CH_FLOW"
+	ChAssign   LexemeType = "CH_ASSIGN"
+	ChEllipsis LexemeType = "CH_ELLIPSIS"
+	Id         LexemeType = "ID"
+	Keyword    LexemeType = "KEYWORD"
+	LParen     LexemeType = "LPAREN"
+	RParen     LexemeType = "RPAREN"
+	LBrace     LexemeType = "LBRACE"
+	RBrace     LexemeType = "RBRACE"
+	// ChSemicolon and Newline terminate statements
+	ChSemicolon LexemeType = "CH_SEMICOLON"
+	Newline     LexemeType = "NEWLINE"
 )
 
 func (l LexemeType) IsSymbol() bool {
@@ -31,11 +49,41 @@ func (l LexemeType) IsSymbol() bool {
 		return true
 	}
 
+	return l.IsComparison()
+}
+
+func (l LexemeType) IsComparison() bool {
+	switch l {
+	case OpLess, OpGreater, OpLessEqual, OpGreaterEqual, OpEquals, OpNotEquals:
+		return true
+	}
+
 	return false
 }
 
 func (l LexemeType) FollowingSymCanBeUnary() bool {
-	return l.IsSymbol() || l == Untyped || l == LParen
+	switch l {
+	case Untyped, LParen, LBrace, ChComma, ChEqual, ChFlow, ChAssign, UnRoot, ChSemicolon, Newline:
+		return true
+	}
+
+	return l.IsSymbol()
+}
+
+// EndsExpression reports whether an expression may end with the lexeme. A line
+// break following such a lexeme terminates the statement
+func (l LexemeType) EndsExpression() bool {
+	switch l {
+	case Number, Id, RParen, RBrace:
+		return true
+	}
+
+	return false
+}
+
+// IsSeparator reports whether the lexeme terminates a statement
+func (l LexemeType) IsSeparator() bool {
+	return l == ChSemicolon || l == Newline
 }
 
 func (l LexemeType) AsUnary() LexemeType {
@@ -44,6 +92,8 @@ func (l LexemeType) AsUnary() LexemeType {
 		return UnPlus
 	case OpMinus:
 		return UnMinus
+	case UnRoot:
+		return UnRoot
 	}
 
 	return Untyped
@@ -62,6 +112,12 @@ func (l Lexeme) String() string {
 	return fmt.Sprintf("(%s %s)", l.Type, l.Value)
 }
 
+// Position is a zero-based line and character in the input
 type Position struct {
 	Line, Char int
 }
+
+// String returns the position as line:char, both counted from one
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line+1, p.Char+1)
+}
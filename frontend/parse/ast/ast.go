@@ -2,6 +2,7 @@ package ast
 
 import (
 	"calculator/frontend/lex"
+	"strings"
 )
 
 type Program []Node
@@ -19,18 +20,194 @@ type (
 		Op    lex.LexemeType
 		Value Node
 	}
+	List     = []Node
 	Function = func(...Node) (Node, error)
-	FCall    struct {
+	// Closure is a function defined by the user, remembering its definition.
+	// Clauses are all of its definitions, including Def being the first one
+	Closure struct {
+		Def     FDef
+		Clauses []FDef
+		Fn      Function
+	}
+	// NamedFunction is a builtin declaring names of its parameters, so it may be
+	// called with named arguments. Name is the name it's bound to, which is set
+	// by interpreters unless it's set already
+	NamedFunction struct {
+		Name  string
+		Names []string
+		Fn    Function
+	}
+	// Impure is a builtin having side effects, like printing. Functions calling
+	// it can't be memoized. Name is set like the one of NamedFunction
+	Impure struct {
+		Name string
+		Fn   Function
+	}
+	// Limited is a builtin, which may run long on its own, like nsum looping over
+	// integers. It accounts its work by the budget, so limits of the evaluation
+	// apply to it. Interpreters turn it into a Function, or into a NamedFunction
+	// if it declares names of its parameters
+	Limited struct {
+		Names []string
+		Fn    func(budget Budget, args ...Node) (Node, error)
+	}
+	// Form is a function receiving its arguments unevaluated, so it can bind
+	// names on its own, like solve binds the unknown
+	Form  = func(env Env, args ...Node) (Node, error)
+	FCall struct {
 		Target Node
 		Args   []Node
 	}
+	// FDef and Def carry the comments preceding the definition as its Doc
 	FDef struct {
 		Name string
 		Args []string
-		Body Node
+		// Defaults are default values of the arguments, nil for required ones.
+		// It's nil unless any argument has a default value
+		Defaults []Node
+		// Variadic means the last argument is bound to the list of the remaining
+		// arguments of a call
+		Variadic bool
+		// Patterns are literal numbers the arguments must be equal to, nil for
+		// arguments matching any value. Names of the pattern arguments are empty.
+		// It's nil unless any argument is a pattern
+		Patterns []Node
+		// Guard is the condition the arguments must satisfy, if any. Functions
+		// may consist of several definitions, called clauses, tried in order
+		// until the one with the patterns and the guard matching is found
+		Guard Node
+		Body  Node
+		Doc   string
+		// Memo means results of the function are cached by values of the arguments
+		Memo bool
 	}
+	// Def defines the name. Constants can't be redefined or assigned to
 	Def struct {
 		Name  string
 		Value Node
+		Doc   string
+		Const bool
+	}
+	// Assign changes the value of the name, defined already
+	Assign struct {
+		Name  string
+		Value Node
+	}
+	Equation struct {
+		Left, Right Node
+	}
+	// NamedArg is an argument of a call, bound to the parameter by its name
+	NamedArg struct {
+		Name  string
+		Value Node
+	}
+	// Spread passes elements of the list as separate arguments of a call
+	Spread struct {
+		Value Node
+	}
+	// Block is a sequence of statements, evaluated in a scope of their own. Its
+	// value is the value of the last statement
+	Block struct {
+		Body []Node
+	}
+	// Literal is a number written differently from its canonical form, like 0x1F,
+	// 1_000 or 6.02e23. The text is kept, so formatting preserves it
+	Literal struct {
+		Value Node
+		Radix int
+		Text  string
 	}
 )
+
+func (c Closure) String() string {
+	return c.Def.Signature()
+}
+
+func (f NamedFunction) String() string {
+	return builtinString(f.Name, strings.Join(f.Names, ", "))
+}
+
+func (f Impure) String() string {
+	return builtinString(f.Name, "...")
+}
+
+// builtinString tells builtins from functions defined by the user, as they have
+// no source to print
+func builtinString(name, params string) string {
+	if len(name) == 0 {
+		return "<builtin (" + params + ")>"
+	}
+
+	return "<builtin " + name + "(" + params + ")>"
+}
+
+// Total reports whether the definition matches any arguments, so following ones
+// can't extend it by more clauses
+func (f FDef) Total() bool {
+	return f.Patterns == nil && f.Guard == nil
+}
+
+// Arity returns the range of the number of arguments the function accepts. The
+// maximum is -1 for variadic functions
+func (f FDef) Arity() (min, max int) {
+	max = len(f.Args)
+	if f.Variadic {
+		max = -1
+	}
+
+	for index := range f.Args {
+		if f.Variadic && index == len(f.Args)-1 {
+			break
+		}
+
+		if f.Defaults == nil || f.Defaults[index] == nil {
+			min++
+		}
+	}
+
+	return min, max
+}
+
+// Callee returns the Go function behind a callable value
+func Callee(node Node) (Function, bool) {
+	switch fn := node.(type) {
+	case Function:
+		return fn, true
+	case Closure:
+		return fn.Fn, true
+	case NamedFunction:
+		return fn.Fn, true
+	case Impure:
+		return fn.Fn, true
+	}
+
+	return nil, false
+}
+
+// Budget accounts work done by builtins against limits of the evaluation
+type Budget interface {
+	// Step accounts the steps done, failing once the evaluation exceeds its
+	// limits or is canceled
+	Step(steps int) error
+	// CheckBits fails if numbers of the bit length are larger than allowed
+	CheckBits(bits int) error
+}
+
+// Unlimited is the budget of evaluations having no limits
+var Unlimited Budget = unlimited{}
+
+type unlimited struct{}
+
+func (unlimited) Step(int) error {
+	return nil
+}
+
+func (unlimited) CheckBits(int) error {
+	return nil
+}
+
+// Env evaluates arguments of a Form in the scope of its caller
+type Env interface {
+	// Evaluate evaluates the node with the names bound in addition
+	Evaluate(node Node, names map[string]Node) (Node, error)
+}
@@ -0,0 +1,84 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump returns the tree as an indented text, a node per line
+func Dump(node Node) string {
+	var b strings.Builder
+	dump(&b, node, 0)
+
+	return b.String()
+}
+
+func dump(b *strings.Builder, node Node, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+
+	switch n := node.(type) {
+	case Integer:
+		fmt.Fprintf(b, "Integer %d\n", n)
+	case Float:
+		fmt.Fprintf(b, "Float %v\n", n)
+	case Literal:
+		fmt.Fprintf(b, "Literal %s (radix %d)\n", n.Text, n.Radix)
+		dump(b, n.Value, depth+1)
+	case ID:
+		fmt.Fprintf(b, "ID %s\n", n)
+	case UnOp:
+		fmt.Fprintf(b, "UnOp %s\n", n.Op)
+		dump(b, n.Value, depth+1)
+	case BinOp:
+		fmt.Fprintf(b, "BinOp %s\n", n.Op)
+		dump(b, n.Left, depth+1)
+		dump(b, n.Right, depth+1)
+	case FCall:
+		b.WriteString("FCall\n")
+		dump(b, n.Target, depth+1)
+		for _, arg := range n.Args {
+			dump(b, arg, depth+1)
+		}
+	case FDef:
+		b.WriteString("FDef " + n.Signature())
+		if n.Guard != nil {
+			b.WriteString(" if " + Format(n.Guard))
+		}
+
+		if n.Memo {
+			b.WriteString(" (memo)")
+		}
+
+		b.WriteString("\n")
+
+		dump(b, n.Body, depth+1)
+	case Def:
+		if n.Const {
+			fmt.Fprintf(b, "Def %s (const)\n", n.Name)
+		} else {
+			fmt.Fprintf(b, "Def %s\n", n.Name)
+		}
+
+		dump(b, n.Value, depth+1)
+	case Assign:
+		fmt.Fprintf(b, "Assign %s\n", n.Name)
+		dump(b, n.Value, depth+1)
+	case Equation:
+		b.WriteString("Equation\n")
+		dump(b, n.Left, depth+1)
+		dump(b, n.Right, depth+1)
+	case NamedArg:
+		fmt.Fprintf(b, "NamedArg %s\n", n.Name)
+		dump(b, n.Value, depth+1)
+	case Spread:
+		b.WriteString("Spread\n")
+		dump(b, n.Value, depth+1)
+	case Block:
+		b.WriteString("Block\n")
+		for _, stmt := range n.Body {
+			dump(b, stmt, depth+1)
+		}
+	default:
+		fmt.Fprintf(b, "%T %v\n", n, n)
+	}
+}
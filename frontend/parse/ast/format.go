@@ -0,0 +1,191 @@
+package ast
+
+import (
+	"calculator/frontend/lex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// precedence of nodes, from the loosest to the tightest binding
+const (
+	precDef = iota
+	precEquation
+	precCompare
+	precSum
+	precProduct
+	precUnary
+	precPower
+	precCall
+)
+
+// Format returns the source code of the node. Parsing it results in the same tree
+func Format(node Node) string {
+	switch n := node.(type) {
+	case Integer:
+		return strconv.FormatInt(n, 10)
+	case Float:
+		return formatFloat(n)
+	case Literal:
+		return n.Text
+	case ID:
+		return n
+	case UnOp:
+		return unarySymbol(n.Op) + operand(n.Value, precPower, false)
+	case BinOp:
+		prec := precedence(n)
+		// power is right-associative, all the others are left-associative
+		rightAssoc := n.Op == lex.OpCaret
+		return operand(n.Left, prec, rightAssoc) + " " + binarySymbol(n.Op) + " " +
+			operand(n.Right, prec, !rightAssoc)
+	case FCall:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = Format(arg)
+		}
+
+		return operand(n.Target, precCall, false) + "(" + strings.Join(args, ", ") + ")"
+	case FDef:
+		signature := n.Signature()
+		if n.Memo {
+			signature = "memo " + signature
+		}
+
+		if n.Guard != nil {
+			return signature + " if " + Format(n.Guard) + " -> " + Format(n.Body)
+		}
+
+		return signature + " -> " + Format(n.Body)
+	case Def:
+		if n.Const {
+			return "const " + n.Name + " -> " + Format(n.Value)
+		}
+
+		return n.Name + " -> " + Format(n.Value)
+	case Assign:
+		return n.Name + " := " + Format(n.Value)
+	case Equation:
+		return operand(n.Left, precEquation, true) + " = " + Format(n.Right)
+	case Spread:
+		return operand(n.Value, precCall, false) + "..."
+	case NamedArg:
+		return n.Name + " = " + Format(n.Value)
+	case Block:
+		stmts := make([]string, len(n.Body))
+		for i, stmt := range n.Body {
+			stmts[i] = Format(stmt)
+		}
+
+		return "{ " + strings.Join(stmts, "; ") + " }"
+	}
+
+	return fmt.Sprint(node)
+}
+
+// Signature returns the name of the function along with its arguments, like
+// f(x, base -> 10, xs...)
+func (f FDef) Signature() string {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		switch {
+		case f.Patterns != nil && f.Patterns[i] != nil:
+			args[i] = Format(f.Patterns[i])
+		case f.Variadic && i == len(f.Args)-1:
+			args[i] = arg + "..."
+		case f.Defaults != nil && f.Defaults[i] != nil:
+			args[i] = arg + " -> " + Format(f.Defaults[i])
+		default:
+			args[i] = arg
+		}
+	}
+
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// formatFloat formats the float so it is parsed back as a float, rather than
+// an integer
+func formatFloat(f Float) string {
+	str := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.ContainsAny(str, ".eIN") {
+		return str
+	}
+
+	return str + ".0"
+}
+
+// operand formats the node, wrapping it in parenthesis if it binds looser than
+// its parent. If strict is set, nodes of the same precedence are wrapped too
+func operand(node Node, parent int, strict bool) string {
+	str := Format(node)
+	if prec := precedence(node); prec < parent || (strict && prec == parent) {
+		return "(" + str + ")"
+	}
+
+	return str
+}
+
+func precedence(node Node) int {
+	switch n := node.(type) {
+	case Def, FDef, Assign:
+		return precDef
+	case Equation:
+		return precEquation
+	case UnOp:
+		return precUnary
+	case BinOp:
+		switch {
+		case n.Op.IsComparison():
+			return precCompare
+		case n.Op == lex.OpPlus || n.Op == lex.OpMinus:
+			return precSum
+		case n.Op == lex.OpStar || n.Op == lex.OpSlash:
+			return precProduct
+		}
+
+		return precPower
+	}
+
+	return precCall
+}
+
+func unarySymbol(op lex.LexemeType) string {
+	switch op {
+	case lex.UnPlus:
+		return "+"
+	case lex.UnMinus:
+		return "-"
+	case lex.UnRoot:
+		return "√"
+	}
+
+	return string(op)
+}
+
+func binarySymbol(op lex.LexemeType) string {
+	switch op {
+	case lex.OpPlus:
+		return "+"
+	case lex.OpMinus:
+		return "-"
+	case lex.OpStar:
+		return "*"
+	case lex.OpSlash:
+		return "/"
+	case lex.OpCaret:
+		return "^"
+	case lex.OpLess:
+		return "<"
+	case lex.OpGreater:
+		return ">"
+	case lex.OpLessEqual:
+		return "<="
+	case lex.OpGreaterEqual:
+		return ">="
+	case lex.OpEquals:
+		return "=="
+	case lex.OpNotEquals:
+		return "!="
+	}
+
+	return string(op)
+}
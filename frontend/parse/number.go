@@ -0,0 +1,61 @@
+package parse
+
+import (
+	"calculator/frontend/parse/ast"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseNumber parses the numeric literal, validated by the lexer. Literals not in
+// their canonical form are wrapped into ast.Literal, keeping the original text
+func parseNumber(text string) (ast.Node, error) {
+	digits := strings.ReplaceAll(text, "_", "")
+	radix := 10
+	if len(digits) > 1 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			radix = 16
+		case 'o', 'O':
+			radix = 8
+		case 'b', 'B':
+			radix = 2
+		}
+
+		if radix != 10 {
+			digits = digits[2:]
+		}
+	}
+
+	var value ast.Node
+	if radix == 10 && strings.ContainsAny(digits, ".eE") {
+		f, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return nil, numberError(text, err)
+		}
+
+		value = f
+	} else {
+		n, err := strconv.ParseInt(digits, radix, 64)
+		if err != nil {
+			return nil, numberError(text, err)
+		}
+
+		value = n
+	}
+
+	if ast.Format(value) == text {
+		return value, nil
+	}
+
+	return ast.Literal{Value: value, Radix: radix, Text: text}, nil
+}
+
+func numberError(text string, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("number out of range: %s", text)
+	}
+
+	return fmt.Errorf("malformed number %s", text)
+}
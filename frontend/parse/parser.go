@@ -3,33 +3,258 @@ package parse
 import (
 	"calculator/frontend/lex"
 	"calculator/frontend/parse/ast"
+	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"strings"
 )
 
 type Parser struct {
-	lexer *lex.Lexer
+	lexer     *lex.Lexer
+	positions []lex.Position
+}
+
+// Error is a syntax error along with the position of the lexeme it was met at
+type Error struct {
+	Pos lex.Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 func NewParser(lexer *lex.Lexer) *Parser {
 	return &Parser{lexer: lexer}
 }
 
+// Parse parses the whole input. Returned errors are always of *Error type
 func (p *Parser) Parse() (program ast.Program, err error) {
-	for !p.lexer.EOF() {
-		node, err := p.stmt()
-		if err != nil {
+	for {
+		node, err := p.Next()
+		switch {
+		case errors.Is(err, io.EOF):
+			return program, nil
+		case err != nil:
 			return nil, err
 		}
 
 		program = append(program, node)
 	}
+}
+
+// Next parses a single statement, so the input can be processed incrementally.
+// Statements are terminated by a semicolon or a line break, unless the line
+// break is inside parenthesis or the expression is obviously incomplete, like
+// after an operator. io.EOF is returned once the input is over, other errors
+// are of *Error type
+func (p *Parser) Next() (ast.Node, error) {
+	// the first lexeme is peeked to know where the statement starts. Empty
+	// statements are skipped
+	for {
+		lexeme, err := p.lexer.Next()
+		switch {
+		case err != nil:
+			return nil, &Error{Pos: p.lexer.Position(), Err: err}
+		case lexeme.Type == lex.EOF:
+			return nil, io.EOF
+		}
+
+		if !lexeme.Type.IsSeparator() {
+			break
+		}
+	}
+
+	p.lexer.Back()
+	p.positions = append(p.positions, p.lexer.Position())
+	doc := strings.Join(p.lexer.Trivia(), "\n")
+
+	node, err := p.statement()
+	if err == nil {
+		err = p.terminator(lex.EOF)
+	}
+
+	if err != nil {
+		return nil, &Error{Pos: p.lexer.Position(), Err: err}
+	}
+
+	return withDoc(node, doc), nil
+}
+
+// terminator consumes the separator, ending the statement. The end of the
+// statements sequence, like a closing brace, is left to the caller
+func (p *Parser) terminator(end lex.LexemeType) error {
+	lexeme, err := p.lexer.Next()
+	switch {
+	case err != nil:
+		return err
+	case lexeme.Type == end:
+		p.lexer.Back()
+	case lexeme.Type == lex.EOF:
+		return fmt.Errorf("wanted %s, got %s", end, lexeme)
+	case !lexeme.Type.IsSeparator():
+		return fmt.Errorf("unexpected %s: statements must be separated by a semicolon or a line break", lexeme)
+	}
+
+	return nil
+}
+
+// withDoc attaches comments preceding the statement to it, if it's a definition
+func withDoc(node ast.Node, doc string) ast.Node {
+	switch n := node.(type) {
+	case ast.Def:
+		n.Doc = doc
+		return n
+	case ast.FDef:
+		n.Doc = doc
+		return n
+	}
+
+	return node
+}
+
+// Positions returns where each parsed statement starts
+func (p *Parser) Positions() []lex.Position {
+	return p.positions
+}
+
+// statement parses the stmt, which may be a constant or a memoized function
+// definition
+func (p *Parser) statement() (ast.Node, error) {
+	lexeme, err := p.lexer.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	if lexeme.Type != lex.Keyword || (lexeme.Value != lex.Const && lexeme.Value != lex.Memo) {
+		p.lexer.Back()
+
+		return p.stmt()
+	}
+
+	stmt, err := p.stmt()
+	if err != nil {
+		return nil, err
+	}
+
+	if lexeme.Value == lex.Memo {
+		fdef, ok := stmt.(ast.FDef)
+		if !ok {
+			return nil, fmt.Errorf("memo must be followed by a function definition")
+		}
+
+		fdef.Memo = true
+
+		return fdef, nil
+	}
+
+	def, ok := stmt.(ast.Def)
+	if !ok {
+		return nil, fmt.Errorf("const must be followed by a variable definition")
+	}
+
+	def.Const = true
 
-	return program, nil
+	return def, nil
 }
 
 func (p *Parser) stmt() (ast.Node, error) {
-	expr, err := p.expr()
+	expr, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	lexeme, err := p.lexer.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch lexeme.Type {
+	case lex.ChFlow:
+		switch expr.(type) {
+		case ast.FCall:
+			return p.fdef(expr.(ast.FCall), nil)
+		case ast.ID:
+			value, err := p.stmt()
+			if err != nil {
+				return nil, err
+			}
+
+			return ast.Def{
+				Name:  expr.(ast.ID),
+				Value: value,
+			}, nil
+		default:
+			return nil, fmt.Errorf("cannot define object with name %v", expr)
+		}
+	case lex.ChAssign:
+		name, ok := expr.(ast.ID)
+		if !ok {
+			return nil, fmt.Errorf("cannot assign to %v", expr)
+		}
+
+		value, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.Assign{
+			Name:  name,
+			Value: value,
+		}, nil
+	case lex.ChEqual:
+		right, err := p.stmt()
+		if err != nil {
+			return nil, err
+		}
+
+		return ast.Equation{
+			Left:  expr,
+			Right: right,
+		}, nil
+	case lex.Keyword:
+		if lexeme.Value == lex.If {
+			return p.guarded(expr)
+		}
+	}
+
+	if lexeme.Type.IsSymbol() {
+		return nil, fmt.Errorf("unexpected operator: %s", lexeme)
+	}
+
+	p.lexer.Back()
+
+	return expr, nil
+}
+
+// guarded parses the clause of a function, applying only if the guard holds:
+//
+//	abs(x) if x < 0 -> -x
+func (p *Parser) guarded(expr ast.Node) (ast.Node, error) {
+	fcall, ok := expr.(ast.FCall)
+	if !ok {
+		return nil, fmt.Errorf("cannot guard %v: only function definitions may be guarded", expr)
+	}
+
+	guard, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.match(lex.ChFlow); err != nil {
+		return nil, err
+	}
+
+	return p.fdef(fcall, guard)
+}
+
+// comparison parses comparisons, binding looser than arithmetic
+func (p *Parser) comparison() (ast.Node, error) {
+	left, err := p.sum()
 	if err != nil {
 		return nil, err
 	}
@@ -40,51 +265,54 @@ func (p *Parser) stmt() (ast.Node, error) {
 			return nil, err
 		}
 
-		if lexeme.Type == lex.EOF {
-			break
+		if !lexeme.Type.IsComparison() {
+			p.lexer.Back()
+
+			return left, nil
 		}
 
-		switch lexeme.Type {
-		case lex.ChFlow:
-			switch expr.(type) {
-			case ast.FCall:
-				return p.fdef(expr.(ast.FCall))
-			case ast.ID:
-				value, err := p.stmt()
-				if err != nil {
-					return nil, err
-				}
-
-				return ast.Def{
-					Name:  expr.(ast.ID),
-					Value: value,
-				}, nil
-			default:
-				return nil, fmt.Errorf("cannot define object with name %v", expr)
-			}
-		case lex.OpPlus, lex.OpMinus:
-			right, err := p.expr()
-			if err != nil {
-				return nil, err
-			}
+		right, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
 
-			expr = ast.BinOp{
-				Op:    lexeme.Type,
-				Left:  expr,
-				Right: right,
-			}
-		default:
-			if lexeme.Type.IsSymbol() {
-				return nil, fmt.Errorf("unexpected operator: %s", lexeme)
-			}
+		left = ast.BinOp{
+			Op:    lexeme.Type,
+			Left:  left,
+			Right: right,
+		}
+	}
+}
+
+func (p *Parser) sum() (ast.Node, error) {
+	left, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		lexeme, err := p.lexer.Next()
+		if err != nil {
+			return nil, err
+		}
 
+		if lexeme.Type != lex.OpPlus && lexeme.Type != lex.OpMinus {
 			p.lexer.Back()
 
-			return expr, nil
+			return left, nil
 		}
-	}
 
-	return expr, nil
+		right, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+
+		left = ast.BinOp{
+			Op:    lexeme.Type,
+			Left:  left,
+			Right: right,
+		}
+	}
 }
 
 func (p *Parser) expr() (ast.Node, error) {
@@ -203,10 +431,10 @@ func (p *Parser) factor() (ast.Node, error) {
 
 	switch lexeme.Type {
 	case lex.Number:
-		return strconv.ParseInt(lexeme.Value, 10, 64)
+		return parseNumber(lexeme.Value)
 	case lex.Id:
 		return lexeme.Value, nil
-	case lex.UnPlus, lex.UnMinus:
+	case lex.UnPlus, lex.UnMinus, lex.UnRoot:
 		value, err := p.power()
 		return ast.UnOp{
 			Op:    lexeme.Type,
@@ -219,11 +447,47 @@ func (p *Parser) factor() (ast.Node, error) {
 		}
 
 		return stmt, p.match(lex.RParen)
+	case lex.LBrace:
+		return p.block()
 	default:
 		return nil, fmt.Errorf("unexpected factor: %s", lexeme)
 	}
 }
 
+// block parses statements up to the closing brace. Like at the top level, they
+// are separated by semicolons or line breaks
+func (p *Parser) block() (ast.Node, error) {
+	var block ast.Block
+	for {
+		lexeme, err := p.lexer.Next()
+		switch {
+		case err != nil:
+			return nil, err
+		case lexeme.Type.IsSeparator():
+			continue
+		case lexeme.Type == lex.RBrace:
+			if len(block.Body) == 0 {
+				return nil, errors.New("empty block")
+			}
+
+			return block, nil
+		case lexeme.Type == lex.EOF:
+			return nil, fmt.Errorf("wanted %s, got %s", lex.RBrace, lexeme)
+		}
+
+		p.lexer.Back()
+		stmt, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+
+		block.Body = append(block.Body, stmt)
+		if err = p.terminator(lex.RBrace); err != nil {
+			return nil, err
+		}
+	}
+}
+
 func (p *Parser) fcall(base ast.Node) (ast.Node, error) {
 	var args []ast.Node
 
@@ -246,6 +510,21 @@ func (p *Parser) fcall(base ast.Node) (ast.Node, error) {
 			return nil, err
 		}
 
+		if lexeme.Type == lex.ChEllipsis {
+			arg = ast.Spread{Value: arg}
+			if lexeme, err = p.lexer.Next(); err != nil {
+				return nil, err
+			}
+		}
+
+		// an equation with a name on the left is an argument passed by name.
+		// Forms, like solve, take it back as the equation
+		if eq, ok := arg.(ast.Equation); ok {
+			if name, ok := eq.Left.(ast.ID); ok {
+				arg = ast.NamedArg{Name: name, Value: eq.Right}
+			}
+		}
+
 		args = append(args, arg)
 
 		switch lexeme.Type {
@@ -261,21 +540,60 @@ func (p *Parser) fcall(base ast.Node) (ast.Node, error) {
 	}
 }
 
-func (p *Parser) fdef(base ast.FCall) (node ast.Node, err error) {
+func (p *Parser) fdef(base ast.FCall, guard ast.Node) (node ast.Node, err error) {
 	name, ok := base.Target.(ast.ID)
 	if !ok {
 		return nil, fmt.Errorf("cannot use %v as a function name", base.Target)
 	}
 
-	fdef := ast.FDef{Name: name}
+	fdef := ast.FDef{Name: name, Guard: guard}
 
-	for _, arg := range base.Args {
-		id, ok := arg.(ast.ID)
-		if !ok {
+	for index, arg := range base.Args {
+		var defaultValue, pattern ast.Node
+		switch arg := arg.(type) {
+		case ast.ID:
+			if fdef.Defaults != nil {
+				return nil, fmt.Errorf("argument %s must have a default value, as the preceding ones do", arg)
+			}
+
+			fdef.Args = append(fdef.Args, arg)
+		case ast.Integer, ast.Float, ast.Literal, ast.UnOp:
+			if !isPattern(arg) || fdef.Defaults != nil {
+				return nil, fmt.Errorf("cannot use %s as a function argument", ast.Format(arg))
+			}
+
+			if fdef.Patterns == nil {
+				fdef.Patterns = make([]ast.Node, len(fdef.Args))
+			}
+
+			fdef.Args = append(fdef.Args, "")
+			pattern = arg
+		case ast.Def:
+			if fdef.Defaults == nil {
+				fdef.Defaults = make([]ast.Node, len(fdef.Args))
+			}
+
+			fdef.Args = append(fdef.Args, arg.Name)
+			defaultValue = arg.Value
+		case ast.Spread:
+			id, ok := arg.Value.(ast.ID)
+			if !ok || index != len(base.Args)-1 {
+				return nil, fmt.Errorf("cannot use %s as a function argument: only the last one may be variadic", ast.Format(arg))
+			}
+
+			fdef.Args = append(fdef.Args, id)
+			fdef.Variadic = true
+		default:
 			return nil, fmt.Errorf("cannot use %v as a function argument", arg)
 		}
 
-		fdef.Args = append(fdef.Args, id)
+		if fdef.Defaults != nil {
+			fdef.Defaults = append(fdef.Defaults, defaultValue)
+		}
+
+		if fdef.Patterns != nil {
+			fdef.Patterns = append(fdef.Patterns, pattern)
+		}
 	}
 
 	fdef.Body, err = p.stmt()
@@ -283,6 +601,19 @@ func (p *Parser) fdef(base ast.FCall) (node ast.Node, err error) {
 	return fdef, err
 }
 
+// isPattern reports whether the argument of a definition is a literal number,
+// possibly negative
+func isPattern(node ast.Node) bool {
+	switch n := node.(type) {
+	case ast.Integer, ast.Float, ast.Literal:
+		return true
+	case ast.UnOp:
+		return n.Op == lex.UnMinus && isPattern(n.Value)
+	}
+
+	return false
+}
+
 func (p *Parser) match(typ lex.LexemeType) error {
 	lexeme, err := p.lexer.Next()
 	if err != nil {
@@ -5,6 +5,8 @@ import (
 	"calculator/frontend/parse/ast"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 )
 
@@ -136,11 +138,176 @@ func TestParser(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "subtraction is left-associative",
+			Expr: "a-(b-c)-d",
+			Want: ast.BinOp{
+				Op: lex.OpMinus,
+				Left: ast.BinOp{
+					Op:    lex.OpMinus,
+					Left:  "a",
+					Right: ast.BinOp{Op: lex.OpMinus, Left: "b", Right: "c"},
+				},
+				Right: "d",
+			},
+		},
+		{
+			Name: "power is right-associative",
+			Expr: "(-x)^y^2",
+			Want: ast.BinOp{
+				Op:    lex.OpCaret,
+				Left:  ast.UnOp{Op: lex.UnMinus, Value: "x"},
+				Right: ast.BinOp{Op: lex.OpCaret, Left: "y", Right: ast.Integer(2)},
+			},
+		},
+		{
+			Name: "equation",
+			Expr: "x^2 - 2 = -1",
+			Want: ast.Equation{
+				Left: ast.BinOp{
+					Op:    lex.OpMinus,
+					Left:  ast.BinOp{Op: lex.OpCaret, Left: "x", Right: ast.Integer(2)},
+					Right: ast.Integer(2),
+				},
+				Right: ast.UnOp{Op: lex.UnMinus, Value: ast.Integer(1)},
+			},
+		},
+		{
+			Name: "default and variadic arguments",
+			Expr: "f(x, base -> 10, xs...) -> g(xs..., base)",
+			Want: ast.FDef{
+				Name:     "f",
+				Args:     []string{"x", "base", "xs"},
+				Defaults: []ast.Node{nil, ast.Integer(10), nil},
+				Variadic: true,
+				Body: ast.FCall{
+					Target: "g",
+					Args:   []ast.Node{ast.Spread{Value: "xs"}, "base"},
+				},
+			},
+		},
+		{
+			Name: "comparison binds looser than arithmetic",
+			Expr: "a + 1 < b * 2 == 1",
+			Want: ast.BinOp{
+				Op: lex.OpEquals,
+				Left: ast.BinOp{
+					Op:    lex.OpLess,
+					Left:  ast.BinOp{Op: lex.OpPlus, Left: "a", Right: ast.Integer(1)},
+					Right: ast.BinOp{Op: lex.OpStar, Left: "b", Right: ast.Integer(2)},
+				},
+				Right: ast.Integer(1),
+			},
+		},
+		{
+			Name: "pattern",
+			Expr: "fib(0, -1, n) -> n",
+			Want: ast.FDef{
+				Name:     "fib",
+				Args:     []string{"", "", "n"},
+				Patterns: []ast.Node{ast.Integer(0), ast.UnOp{Op: lex.UnMinus, Value: ast.Integer(1)}, nil},
+				Body:     "n",
+			},
+		},
+		{
+			Name: "guard",
+			Expr: "abs(x) if x < 0 -> -x",
+			Want: ast.FDef{
+				Name:  "abs",
+				Args:  []string{"x"},
+				Guard: ast.BinOp{Op: lex.OpLess, Left: "x", Right: ast.Integer(0)},
+				Body:  ast.UnOp{Op: lex.UnMinus, Value: "x"},
+			},
+		},
+		{
+			Name: "named arguments",
+			Expr: "pmt(rate, amount = 1000, periods = n * 12)",
+			Want: ast.FCall{
+				Target: "pmt",
+				Args: []ast.Node{
+					"rate",
+					ast.NamedArg{Name: "amount", Value: ast.Integer(1000)},
+					ast.NamedArg{Name: "periods", Value: ast.BinOp{Op: lex.OpStar, Left: "n", Right: ast.Integer(12)}},
+				},
+			},
+		},
+		{
+			Name: "assignment",
+			Expr: "x := y := x + 1",
+			Want: ast.Assign{
+				Name:  "x",
+				Value: ast.Assign{Name: "y", Value: ast.BinOp{Op: lex.OpPlus, Left: "x", Right: ast.Integer(1)}},
+			},
+		},
+		{
+			Name: "constant",
+			Expr: "const g -> 9.81",
+			Want: ast.Def{Name: "g", Value: ast.Float(9.81), Const: true},
+		},
+		{
+			Name: "memoized function",
+			Expr: "memo fib(n) if n > 1 -> fib(n-1) + fib(n-2)",
+			Want: ast.FDef{
+				Name:  "fib",
+				Args:  []string{"n"},
+				Guard: ast.BinOp{Op: lex.OpGreater, Left: "n", Right: ast.Integer(1)},
+				Body: ast.BinOp{
+					Op:    lex.OpPlus,
+					Left:  ast.FCall{Target: "fib", Args: []ast.Node{ast.BinOp{Op: lex.OpMinus, Left: "n", Right: ast.Integer(1)}}},
+					Right: ast.FCall{Target: "fib", Args: []ast.Node{ast.BinOp{Op: lex.OpMinus, Left: "n", Right: ast.Integer(2)}}},
+				},
+				Memo: true,
+			},
+		},
+		{
+			Name: "block",
+			Expr: "f(x) -> { a -> x^2; a * 2 }",
+			Want: ast.FDef{
+				Name: "f",
+				Args: []string{"x"},
+				Body: ast.Block{Body: []ast.Node{
+					ast.Def{Name: "a", Value: ast.BinOp{Op: lex.OpCaret, Left: "x", Right: ast.Integer(2)}},
+					ast.BinOp{Op: lex.OpStar, Left: "a", Right: ast.Integer(2)},
+				}},
+			},
+		},
+		{
+			Name: "number literals",
+			Expr: "0x1F + 1_000 * 2.5e3",
+			Want: ast.BinOp{
+				Op:   lex.OpPlus,
+				Left: ast.Literal{Value: ast.Integer(31), Radix: 16, Text: "0x1F"},
+				Right: ast.BinOp{
+					Op:    lex.OpStar,
+					Left:  ast.Literal{Value: ast.Integer(1000), Radix: 10, Text: "1_000"},
+					Right: ast.Literal{Value: ast.Float(2500), Radix: 10, Text: "2.5e3"},
+				},
+			},
+		},
+		{
+			Name: "square root of superscript power",
+			Expr: "√x² × 2",
+			Want: ast.BinOp{
+				Op: lex.OpStar,
+				Left: ast.UnOp{
+					Op:    lex.UnRoot,
+					Value: ast.BinOp{Op: lex.OpCaret, Left: "x", Right: ast.Integer(2)},
+				},
+				Right: ast.Integer(2),
+			},
+		},
 	}
 
 	for _, tc := range tcs {
 		testParser(t, fmtTestName(tc.Name, tc.Expr), tc.Expr, []ast.Node{tc.Want})
 	}
+
+	t.Run("format", func(t *testing.T) {
+		for _, tc := range tcs {
+			formatted := ast.Format(tc.Want)
+			testParser(t, fmtTestName(tc.Name, formatted), formatted, []ast.Node{tc.Want})
+		}
+	})
 }
 
 func testParser(t *testing.T, name, code string, want ast.Program) {
@@ -157,3 +324,123 @@ func testParser(t *testing.T, name, code string, want ast.Program) {
 func fmtTestName(name, code string) string {
 	return fmt.Sprintf("%s (%s)", name, code)
 }
+
+func TestPositions(t *testing.T) {
+	parser := NewParser(lex.NewLexer("a -> 1\n  b"))
+	_, err := parser.Parse()
+	require.NoError(t, err)
+	require.Equal(t, []lex.Position{{Line: 0, Char: 0}, {Line: 1, Char: 2}}, parser.Positions())
+
+	_, err = NewParser(lex.NewLexer("a -> 1\nb -> )")).Parse()
+	var parseErr *Error
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, lex.Position{Line: 1, Char: 5}, parseErr.Pos)
+	require.EqualError(t, err, "unexpected factor: (RPAREN ))")
+}
+
+func TestDoc(t *testing.T) {
+	tree, err := NewParser(lex.NewLexer("# rate\n# in percents\nr -> 5\n\n/* tax */ tax(x) -> x*r/100\n# not a doc\ntax(1)")).Parse()
+	require.NoError(t, err)
+	require.Equal(t, ast.Program{
+		ast.Def{Name: "r", Value: ast.Integer(5), Doc: "rate\nin percents"},
+		ast.FDef{
+			Name: "tax",
+			Args: []string{"x"},
+			Body: ast.BinOp{
+				Op:    lex.OpSlash,
+				Left:  ast.BinOp{Op: lex.OpStar, Left: "x", Right: "r"},
+				Right: ast.Integer(100),
+			},
+			Doc: "tax",
+		},
+		ast.FCall{Target: "tax", Args: []ast.Node{ast.Integer(1)}},
+	}, tree)
+}
+
+func TestSeparators(t *testing.T) {
+	tree, err := NewParser(lex.NewLexer(";a -> 1; b -> f(a,\n  2) +\n 3;;\n\n-a\n")).Parse()
+	require.NoError(t, err)
+	require.Equal(t, ast.Program{
+		ast.Def{Name: "a", Value: ast.Integer(1)},
+		ast.Def{Name: "b", Value: ast.BinOp{
+			Op:    lex.OpPlus,
+			Left:  ast.FCall{Target: "f", Args: []ast.Node{"a", ast.Integer(2)}},
+			Right: ast.Integer(3),
+		}},
+		ast.UnOp{Op: lex.UnMinus, Value: "a"},
+	}, tree)
+
+	tree, err = NewParser(lex.NewLexer("{\n  a -> (1 +\n 2)\n\n  -a\n}\n{ 1 }")).Parse()
+	require.NoError(t, err)
+	require.Equal(t, ast.Program{
+		ast.Block{Body: []ast.Node{
+			ast.Def{Name: "a", Value: ast.BinOp{Op: lex.OpPlus, Left: ast.Integer(1), Right: ast.Integer(2)}},
+			ast.UnOp{Op: lex.UnMinus, Value: "a"},
+		}},
+		ast.Block{Body: []ast.Node{ast.Integer(1)}},
+	}, tree)
+
+	for code, want := range map[string]string{
+		"{}":          "empty block",
+		"{ 1; 2":      "wanted RBRACE, got (EOF)",
+		"{ 1 2 }":     "unexpected (NUMBER 2): statements must be separated by a semicolon or a line break",
+		"memo x -> 1": "memo must be followed by a function definition",
+	} {
+		_, err = NewParser(lex.NewLexer(code)).Parse()
+		require.EqualError(t, err, want, code)
+	}
+
+	for code, pos := range map[string]lex.Position{
+		"1 2":            {Line: 0, Char: 2},
+		"a -> 1 b -> 2":  {Line: 0, Char: 7},
+		"f(x) -> x f(2)": {Line: 0, Char: 10},
+		"a -> 1\n(1) 2":  {Line: 1, Char: 4},
+	} {
+		_, err = NewParser(lex.NewLexer(code)).Parse()
+		var parseErr *Error
+		require.ErrorAs(t, err, &parseErr, code)
+		require.Equal(t, pos, parseErr.Pos, code)
+		require.ErrorContains(t, err, "statements must be separated by a semicolon or a line break", code)
+	}
+}
+
+func TestNumberErrors(t *testing.T) {
+	_, err := NewParser(lex.NewLexer("1 + 0x")).Parse()
+	var parseErr *Error
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, lex.Position{Line: 0, Char: 4}, parseErr.Pos)
+	require.EqualError(t, err, "malformed number 0x: no digits after 0x")
+
+	for code, want := range map[string]string{
+		"2 * 1. + 3":  "malformed number 1.: missing digits after decimal point",
+		"2 * 1e + 3":  "malformed number 1e: missing exponent digits",
+		"2 * 0x1.8p1": "malformed number 0x1.8p1: fractional part isn't allowed in base 16",
+		"2 * 1.5e-":   "malformed number 1.5e-: missing exponent digits",
+	} {
+		_, err = NewParser(lex.NewLexer(code)).Parse()
+		require.ErrorAs(t, err, &parseErr, code)
+		require.Equal(t, lex.Position{Line: 0, Char: 4}, parseErr.Pos, code)
+		require.EqualError(t, err, want, code)
+	}
+
+	_, err = NewParser(lex.NewLexer("0x8000000000000000")).Parse()
+	require.EqualError(t, err, "number out of range: 0x8000000000000000")
+}
+
+func TestNext(t *testing.T) {
+	parser := NewParser(lex.NewReaderLexer(strings.NewReader("a -> 1\nb -> a +\n2\nc -> )")))
+
+	node, err := parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, ast.Def{Name: "a", Value: ast.Integer(1)}, node)
+
+	node, err = parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, ast.Def{Name: "b", Value: ast.BinOp{Op: lex.OpPlus, Left: "a", Right: ast.Integer(2)}}, node)
+	require.Equal(t, lex.Position{Line: 1, Char: 0}, parser.Positions()[1])
+
+	_, err = parser.Next()
+	var parseErr *Error
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, lex.Position{Line: 3, Char: 5}, parseErr.Pos)
+}
@@ -0,0 +1,248 @@
+package calculator
+
+import (
+	"calculator/frontend/parse/ast"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	nodeType  = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	valueType = reflect.TypeOf(Value{})
+)
+
+// Wrap turns an arbitrary Go function into a builtin, like func(float64, int) float64
+// or func(...int64) (int64, error). Parameters may be of integer and float types,
+// slices of them, Value or ast.Node. The function must return a single value of
+// such a type, optionally followed by an error. Arguments are checked and converted
+// on every call, so calling it with wrong ones results in an error
+func Wrap(fn any) (ast.Function, error) {
+	reflected := reflect.ValueOf(fn)
+	if reflected.Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot wrap %T: not a function", fn)
+	}
+
+	typ := reflected.Type()
+	for i := 0; i < typ.NumIn(); i++ {
+		param := typ.In(i)
+		if typ.IsVariadic() && i == typ.NumIn()-1 {
+			param = param.Elem()
+		}
+
+		if !convertible(param) {
+			return nil, fmt.Errorf("cannot wrap %T: unsupported parameter type %s", fn, param)
+		}
+	}
+
+	switch {
+	case typ.NumOut() == 0 || typ.NumOut() > 2:
+		return nil, fmt.Errorf("cannot wrap %T: must return a value and optionally an error", fn)
+	case !convertible(typ.Out(0)):
+		return nil, fmt.Errorf("cannot wrap %T: unsupported result type %s", fn, typ.Out(0))
+	case typ.NumOut() == 2 && typ.Out(1) != errorType:
+		return nil, fmt.Errorf("cannot wrap %T: second result must be an error", fn)
+	}
+
+	return func(args ...ast.Node) (ast.Node, error) {
+		in, err := convertArgs(typ, args)
+		if err != nil {
+			return nil, err
+		}
+
+		out := reflected.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+
+		if typ.Out(0) == nodeType {
+			return out[0].Interface(), nil
+		}
+
+		result, err := ValueOf(out[0].Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		return result.node, nil
+	}, nil
+}
+
+// WrapNamed is like Wrap, but also declares names of the parameters, so the
+// function may be called with named arguments, like pmt(rate = 5, periods = 12).
+// Every parameter, apart from the variadic one, must be named
+func WrapNamed(fn any, names ...string) (ast.NamedFunction, error) {
+	wrapped, err := Wrap(fn)
+	if err != nil {
+		return ast.NamedFunction{}, err
+	}
+
+	params := reflect.TypeOf(fn).NumIn()
+	if reflect.TypeOf(fn).IsVariadic() {
+		params--
+	}
+
+	if len(names) != params {
+		return ast.NamedFunction{}, fmt.Errorf("cannot wrap %T: wanted %d parameter names, got %d instead", fn, params, len(names))
+	}
+
+	return ast.NamedFunction{Names: names, Fn: wrapped}, nil
+}
+
+// MustWrap is like Wrap, but panics if the function can't be wrapped
+func MustWrap(fn any) ast.Function {
+	wrapped, err := Wrap(fn)
+	if err != nil {
+		panic(err)
+	}
+
+	return wrapped
+}
+
+func convertArgs(typ reflect.Type, args []ast.Node) ([]reflect.Value, error) {
+	fixed := typ.NumIn()
+	if typ.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("wanted at least %d args, got %d instead", fixed, len(args))
+		}
+	} else if len(args) != fixed {
+		return nil, fmt.Errorf("wanted %d args, got %d instead", fixed, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var param reflect.Type
+		if i < fixed {
+			param = typ.In(i)
+		} else {
+			param = typ.In(fixed).Elem()
+		}
+
+		converted, err := convert(arg, param)
+		if err != nil {
+			return nil, fmt.Errorf("arg %d: %w", i+1, err)
+		}
+
+		in[i] = converted
+	}
+
+	return in, nil
+}
+
+// convertible reports whether values of the type can be converted from and into
+// calculator values
+func convertible(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return convertible(typ.Elem())
+	}
+
+	return typ == valueType || typ == nodeType
+}
+
+// convert converts the calculator value into a Go value of the type
+func convert(node ast.Node, typ reflect.Type) (reflect.Value, error) {
+	mismatch := &TypeError{Value: node, Wanted: typ.String()}
+
+	switch {
+	case typ == valueType:
+		return reflect.ValueOf(Value{node}), nil
+	case typ == nodeType:
+		value := reflect.New(typ).Elem()
+		if node != nil {
+			value.Set(reflect.ValueOf(node))
+		}
+
+		return value, nil
+	}
+
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := node.(ast.Integer)
+		if !ok || value.OverflowInt(n) {
+			return value, mismatch
+		}
+
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := node.(ast.Integer)
+		if !ok || n < 0 || value.OverflowUint(uint64(n)) {
+			return value, mismatch
+		}
+
+		value.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := Value{node}.Float()
+		if err != nil {
+			return value, mismatch
+		}
+
+		value.SetFloat(f)
+	case reflect.Slice:
+		list, ok := node.(ast.List)
+		if !ok {
+			return value, mismatch
+		}
+
+		value = reflect.MakeSlice(typ, len(list), len(list))
+		for i, elem := range list {
+			converted, err := convert(elem, typ.Elem())
+			if err != nil {
+				return value, mismatch
+			}
+
+			value.Index(i).Set(converted)
+		}
+	default:
+		return value, mismatch
+	}
+
+	return value, nil
+}
+
+// fromGo converts numbers of any Go numeric type, slices of them and functions
+// into calculator values
+func fromGo(value reflect.Value) (Value, error) {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return Value{}, &TypeError{Value: nil, Wanted: "calculator value"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Value{value.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Uint() > math.MaxInt64 {
+			return Value{}, &TypeError{Value: value.Interface(), Wanted: "integer"}
+		}
+
+		return Value{ast.Integer(value.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return Value{value.Float()}, nil
+	case reflect.Slice:
+		list := make(ast.List, value.Len())
+		for i := range list {
+			elem, err := ValueOf(value.Index(i).Interface())
+			if err != nil {
+				return Value{}, err
+			}
+
+			list[i] = elem.node
+		}
+
+		return Value{list}, nil
+	case reflect.Func:
+		fn, err := Wrap(value.Interface())
+		if err != nil {
+			return Value{}, err
+		}
+
+		return Value{fn}, nil
+	}
+
+	return Value{}, &TypeError{Value: value.Interface(), Wanted: "calculator value"}
+}
@@ -0,0 +1,107 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	calc := New()
+	require.NoError(t, calc.Set("hypot", math.Hypot))
+	require.NoError(t, calc.Set("repeat", func(x float64, times int) float64 {
+		return x * float64(times)
+	}))
+	require.NoError(t, calc.Set("total", func(prices ...int64) (int64, error) {
+		var total int64
+		for _, price := range prices {
+			if price < 0 {
+				return 0, errors.New("negative price")
+			}
+
+			total += price
+		}
+
+		return total, nil
+	}))
+	require.NoError(t, calc.Set("double", func(xs []int) []int {
+		for i := range xs {
+			xs[i] *= 2
+		}
+
+		return xs
+	}))
+
+	require.NoError(t, calc.Set("seq", func(n uint8) []int64 {
+		seq := make([]int64, n)
+		for i := range seq {
+			seq[i] = int64(i)
+		}
+
+		return seq
+	}))
+
+	t.Run("conversion", func(t *testing.T) {
+		for code, want := range map[string]string{
+			"hypot(3, 4)":                 "5",
+			"repeat(hypot(3, 4), 2)":      "10",
+			"total()":                     "0",
+			"total(1, 2, 3)":              "6",
+			"double(seq(3))":              "[0 2 4]",
+			"repeat(2, 3) + total(10, 5)": "21",
+		} {
+			result, err := calc.Eval(code)
+			require.NoError(t, err, code)
+			require.Equal(t, want, result.String(), code)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		for code, want := range map[string]string{
+			"hypot(3)":               "wanted 2 args, got 1 instead",
+			"repeat(1, hypot(0, 2))": "arg 2: cannot use 2 (float64) as int",
+			"seq(-1)":                "arg 1: cannot use -1 (int64) as uint8",
+			"total(1, -2)":           "negative price",
+			"total(1, hypot(1,1))":   "arg 2: cannot use 1.4142135623730951 (float64) as int64",
+			"double(1)":              "arg 1: cannot use 1 (int64) as []int",
+		} {
+			_, err := calc.Eval(code)
+			require.EqualError(t, err, want, code)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		for _, fn := range []any{
+			42,
+			func() {},
+			func(string) int { return 0 },
+			func() (int, int) { return 0, 0 },
+		} {
+			_, err := Wrap(fn)
+			require.Error(t, err)
+		}
+	})
+}
+
+func TestWrapNamed(t *testing.T) {
+	pmt, err := WrapNamed(func(rate, periods, amount float64) float64 {
+		return amount * rate / (1 - math.Pow(1+rate, -periods))
+	}, "rate", "periods", "amount")
+	require.NoError(t, err)
+
+	calc := New()
+	require.NoError(t, calc.Set("pmt", pmt))
+	positional, err := calc.Eval("pmt(0.01, 12, 1000)")
+	require.NoError(t, err)
+	named, err := calc.Eval("pmt(amount = 1000, rate = 0.01, periods = 12)")
+	require.NoError(t, err)
+	require.Equal(t, positional, named)
+
+	_, err = calc.Eval("pmt(0.01, amount = 1000)")
+	require.EqualError(t, err, "missing argument: periods")
+
+	_, err = WrapNamed(math.Hypot, "x")
+	require.EqualError(t, err, "cannot wrap func(float64, float64) float64: wanted 2 parameter names, got 1 instead")
+}
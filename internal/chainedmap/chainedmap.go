@@ -44,6 +44,23 @@ func (c *ChainedMap[K, V]) Update(key K, value V) (updated bool) {
 	return false
 }
 
+// Level returns the level of nesting the key is found at, counting from the top
+// one being zero. If the key isn't found, -1 is returned
+func (c *ChainedMap[K, V]) Level(key K) int {
+	for i := len(c.maps) - 1; i >= 0; i-- {
+		if _, found := c.maps[i][key]; found {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Depth returns the level of the innermost nesting
+func (c *ChainedMap[K, V]) Depth() int {
+	return len(c.maps) - 1
+}
+
 // Pop removes one level of nesting
 func (c *ChainedMap[K, V]) Pop() {
 	if len(c.maps) == 0 {
@@ -57,3 +74,16 @@ func (c *ChainedMap[K, V]) Pop() {
 func (c *ChainedMap[K, V]) Push() {
 	c.maps = append(c.maps, make(map[K]V))
 }
+
+// Items returns all the reachable keys along with their values. Values from the
+// down override the ones from the top
+func (c *ChainedMap[K, V]) Items() map[K]V {
+	items := make(map[K]V)
+	for _, m := range c.maps {
+		for key, value := range m {
+			items[key] = value
+		}
+	}
+
+	return items
+}
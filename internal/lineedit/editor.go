@@ -0,0 +1,368 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInterrupted is returned when Ctrl-C is pressed
+var ErrInterrupted = errors.New("interrupted")
+
+// key is a special key, sent by the terminal as an escape sequence
+type key int
+
+const (
+	keyNone key = iota
+	keyUnknown
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+const (
+	keyBackspace = 127
+	keyEscape    = 0x1b
+)
+
+// Editor reads lines from a terminal, letting them be edited and recalled from
+// the history. If the input isn't a terminal, lines are read as is
+type Editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       uintptr
+	terminal bool
+	history  *History
+}
+
+func New(in *os.File, out io.Writer, history *History) *Editor {
+	e := newEditor(in, out, history)
+	e.fd = in.Fd()
+	e.terminal = IsTerminal(in)
+
+	return e
+}
+
+func newEditor(in io.Reader, out io.Writer, history *History) *Editor {
+	if history == nil {
+		history, _ = LoadHistory("")
+	}
+
+	return &Editor{
+		in:      bufio.NewReader(in),
+		out:     out,
+		history: history,
+	}
+}
+
+// IsTerminal reports whether the file is a terminal
+func IsTerminal(file *os.File) bool {
+	return isTerminal(file)
+}
+
+// Interactive reports whether lines are read from a terminal
+func (e *Editor) Interactive() bool {
+	return e.terminal
+}
+
+// ReadLine reads a line without the trailing line break. io.EOF is returned when
+// the input is over or Ctrl-D is pressed on an empty line, and ErrInterrupted
+// when Ctrl-C is pressed
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	if !e.terminal {
+		return e.readPlain(prompt)
+	}
+
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+
+	defer restore()
+
+	line, err := e.edit(prompt)
+	if err == nil {
+		// history is a convenience, so failing to persist it must not break the input
+		_ = e.history.Add(line)
+	}
+
+	return line, err
+}
+
+func (e *Editor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.in.ReadString('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// line is the state of the line being edited
+type line struct {
+	buf []rune
+	pos int
+}
+
+func (l *line) set(text string) {
+	l.buf = []rune(text)
+	l.pos = len(l.buf)
+}
+
+func (l *line) insert(r rune) {
+	l.buf = append(l.buf, 0)
+	copy(l.buf[l.pos+1:], l.buf[l.pos:])
+	l.buf[l.pos] = r
+	l.pos++
+}
+
+func (l *line) delete() {
+	if l.pos < len(l.buf) {
+		l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+	}
+}
+
+func (l *line) backspace() {
+	if l.pos > 0 {
+		l.pos--
+		l.delete()
+	}
+}
+
+func (e *Editor) edit(prompt string) (string, error) {
+	var (
+		l     line
+		draft string
+		index = len(e.history.Entries())
+	)
+
+	for {
+		e.render(prompt, string(l.buf), l.pos)
+		r, special, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		if r == ctrl('R') {
+			if r, special, err = e.search(&l); err != nil {
+				return "", err
+			}
+		}
+
+		entries := e.history.Entries()
+		switch special {
+		case keyUp:
+			r = ctrl('P')
+		case keyDown:
+			r = ctrl('N')
+		case keyLeft:
+			r = ctrl('B')
+		case keyRight:
+			r = ctrl('F')
+		case keyHome:
+			r = ctrl('A')
+		case keyEnd:
+			r = ctrl('E')
+		case keyDelete:
+			l.delete()
+			continue
+		case keyUnknown:
+			continue
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(l.buf), nil
+		case ctrl('C'):
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case ctrl('D'):
+			if len(l.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+
+			l.delete()
+		case ctrl('A'):
+			l.pos = 0
+		case ctrl('E'):
+			l.pos = len(l.buf)
+		case ctrl('B'):
+			if l.pos > 0 {
+				l.pos--
+			}
+		case ctrl('F'):
+			if l.pos < len(l.buf) {
+				l.pos++
+			}
+		case ctrl('P'):
+			if index > 0 {
+				if index == len(entries) {
+					draft = string(l.buf)
+				}
+
+				index--
+				l.set(entries[index])
+			}
+		case ctrl('N'):
+			if index < len(entries) {
+				index++
+				if index == len(entries) {
+					l.set(draft)
+				} else {
+					l.set(entries[index])
+				}
+			}
+		case ctrl('K'):
+			l.buf = l.buf[:l.pos]
+		case ctrl('U'):
+			l.buf = l.buf[l.pos:]
+			l.pos = 0
+		case ctrl('H'), keyBackspace:
+			l.backspace()
+		default:
+			if unicode.IsPrint(r) {
+				l.insert(r)
+			}
+		}
+	}
+}
+
+// search runs the reverse incremental search over the history. The key which
+// finished the search is returned, so it can be handled as usual
+func (e *Editor) search(l *line) (rune, key, error) {
+	var (
+		query   []rune
+		entries = e.history.Entries()
+		index   = len(entries)
+		match   string
+	)
+
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(entries[i], string(query)) {
+				index, match = i, entries[i]
+				return
+			}
+		}
+	}
+
+	for {
+		prompt := fmt.Sprintf("(reverse-i-search)`%s': ", string(query))
+		e.render(prompt, match, utf8.RuneCountInString(match))
+
+		r, special, err := e.readKey()
+		if err != nil {
+			return 0, keyNone, err
+		}
+
+		switch {
+		case special != keyNone:
+		case r == ctrl('R'):
+			find(index - 1)
+			continue
+		case r == ctrl('G') || r == ctrl('C'):
+			return 0, keyUnknown, nil
+		case r == ctrl('H') || r == keyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				index, match = len(entries), ""
+				find(len(entries) - 1)
+			}
+
+			continue
+		case unicode.IsPrint(r):
+			// the current match may still contain the longer query
+			query = append(query, r)
+			if index == len(entries) {
+				index--
+			}
+
+			find(index)
+			continue
+		}
+
+		if len(match) > 0 {
+			l.set(match)
+		}
+
+		return r, special, nil
+	}
+}
+
+func (e *Editor) render(prompt, text string, pos int) {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(prompt)
+	b.WriteString(text)
+	b.WriteString("\x1b[K\r")
+	if column := utf8.RuneCountInString(prompt) + pos; column > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", column)
+	}
+
+	fmt.Fprint(e.out, b.String())
+}
+
+// readKey reads either a single character or an escape sequence
+func (e *Editor) readKey() (rune, key, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, keyNone, err
+	}
+
+	next, err := e.in.ReadByte()
+	if err != nil {
+		return 0, keyNone, err
+	}
+
+	if next != '[' && next != 'O' {
+		return 0, keyUnknown, nil
+	}
+
+	var seq []byte
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return 0, keyNone, err
+		}
+
+		seq = append(seq, b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		return 0, keyUp, nil
+	case "B":
+		return 0, keyDown, nil
+	case "C":
+		return 0, keyRight, nil
+	case "D":
+		return 0, keyLeft, nil
+	case "H", "1~", "7~":
+		return 0, keyHome, nil
+	case "F", "4~", "8~":
+		return 0, keyEnd, nil
+	case "3~":
+		return 0, keyDelete, nil
+	}
+
+	return 0, keyUnknown, nil
+}
+
+func ctrl(r rune) rune {
+	return r & 0x1f
+}
@@ -0,0 +1,95 @@
+package lineedit
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditor(t *testing.T) {
+	edit := func(t *testing.T, history *History, keys string) (string, error) {
+		e := newEditor(strings.NewReader(keys), io.Discard, history)
+		return e.edit("> ")
+	}
+
+	t.Run("insert in the middle", func(t *testing.T) {
+		line, err := edit(t, nil, "12\x1b[D3\r")
+		require.NoError(t, err)
+		require.Equal(t, "132", line)
+	})
+
+	t.Run("backspace and delete", func(t *testing.T) {
+		line, err := edit(t, nil, "abcd\x7f\x01\x1b[3~\r")
+		require.NoError(t, err)
+		require.Equal(t, "bc", line)
+	})
+
+	t.Run("history navigation", func(t *testing.T) {
+		history, _ := LoadHistory("")
+		require.NoError(t, history.Add("first"))
+		require.NoError(t, history.Add("second"))
+
+		line, err := edit(t, history, "draft\x1b[A\x1b[A\x1b[B!\r")
+		require.NoError(t, err)
+		require.Equal(t, "second!", line)
+
+		line, err = edit(t, history, "draft\x1b[A\x1b[B\r")
+		require.NoError(t, err)
+		require.Equal(t, "draft", line)
+	})
+
+	t.Run("reverse search", func(t *testing.T) {
+		history, _ := LoadHistory("")
+		for _, entry := range []string{"x -> 1", "f(x) -> x", "y -> 2"} {
+			require.NoError(t, history.Add(entry))
+		}
+
+		line, err := edit(t, history, "\x12x\x1b[C+1\r")
+		require.NoError(t, err)
+		require.Equal(t, "f(x) -> x+1", line)
+
+		line, err = edit(t, history, "\x12x\x12\r")
+		require.NoError(t, err)
+		require.Equal(t, "x -> 1", line)
+
+		line, err = edit(t, history, "a\x12y\x07\r")
+		require.NoError(t, err)
+		require.Equal(t, "a", line)
+	})
+
+	t.Run("end of input", func(t *testing.T) {
+		_, err := edit(t, nil, "\x04")
+		require.ErrorIs(t, err, io.EOF)
+
+		_, err = edit(t, nil, "1\x03")
+		require.ErrorIs(t, err, ErrInterrupted)
+	})
+
+	t.Run("plain input", func(t *testing.T) {
+		e := newEditor(strings.NewReader("1+1\n2+2"), io.Discard, nil)
+		for _, want := range []string{"1+1", "2+2"} {
+			line, err := e.ReadLine("> ")
+			require.NoError(t, err)
+			require.Equal(t, want, line)
+		}
+
+		_, err := e.ReadLine("> ")
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calculator", "history")
+	history, err := LoadHistory(path)
+	require.NoError(t, err)
+	for _, entry := range []string{"1", "1", " ", "2"} {
+		require.NoError(t, history.Add(entry))
+	}
+
+	loaded, err := LoadHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, loaded.Entries())
+}
@@ -0,0 +1,101 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// historyLimit is how many of the latest entries are kept
+const historyLimit = 1000
+
+// History keeps entered lines, optionally persisting them into a file
+type History struct {
+	entries []string
+	path    string
+}
+
+// DefaultHistoryPath returns the path of the history file in the user's config dir
+func DefaultHistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "calculator", "history"), nil
+}
+
+// LoadHistory reads the history file, if it exists. New entries are appended
+// to it. Empty path results in a history kept in memory only
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	if len(path) == 0 {
+		return h, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(h.entries) > historyLimit {
+		h.entries = h.entries[len(h.entries)-historyLimit:]
+		content := strings.Join(h.entries, "\n") + "\n"
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// Add appends the line to the history, unless it is empty or repeats the
+// previous one
+func (h *History) Add(line string) error {
+	if len(strings.TrimSpace(line)) == 0 ||
+		(len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.path) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(line + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Entries returns all the lines, from the oldest to the latest
+func (h *History) Entries() []string {
+	return h.entries
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package lineedit
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	termios := new(syscall.Termios)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return termios, nil
+}
+
+func setTermios(fd uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func isTerminal(file *os.File) bool {
+	_, err := getTermios(file.Fd())
+	return err == nil
+}
+
+// makeRaw disables line buffering, echoing and signals of the terminal, so every
+// key press is read as is. The returned function restores the previous state
+func makeRaw(fd uintptr) (restore func(), err error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = setTermios(fd, old)
+	}, nil
+}
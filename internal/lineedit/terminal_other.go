@@ -0,0 +1,19 @@
+//go:build !linux
+
+package lineedit
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal guesses whether the file is a terminal by its mode, as raw mode
+// is implemented only for Linux. Elsewhere, lines are read as is
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func makeRaw(uintptr) (restore func(), err error) {
+	return nil, errors.New("raw mode is not supported on this platform")
+}
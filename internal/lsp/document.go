@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"calculator/backend/interpret"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse"
+	"calculator/frontend/parse/ast"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is an open file along with the results of its analysis
+type document struct {
+	lines []string
+	// idents are all the identifiers met in the text
+	idents []ident
+	// definitions map names defined by the user to where they are defined
+	definitions map[string]lex.Position
+	// docs map names defined by the user to comments preceding the definitions
+	docs map[string]string
+	// names are the names visible after the document was evaluated
+	names       map[string]ast.Node
+	diagnostics []diagnostic
+}
+
+type ident struct {
+	name string
+	pos  lex.Position
+}
+
+// analyze parses and evaluates the text, each statement in order. Statements
+// failing to evaluate don't prevent the following ones from being evaluated. A
+// syntax error ends the analysis, keeping the statements preceding it
+func analyze(text string, interpreter *interpret.Interpreter) *document {
+	doc := &document{
+		lines:       strings.Split(text, "\n"),
+		idents:      scanIdents(text),
+		definitions: make(map[string]lex.Position),
+		docs:        make(map[string]string),
+	}
+
+	// the whole document is analyzed within the limits, so it's done in time
+	defer interpreter.Begin(context.Background())()
+
+	parser := parse.NewParser(lex.NewLexer(text))
+	for {
+		stmt, err := parser.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			pos := lex.Position{}
+			var syntaxErr *parse.Error
+			if errors.As(err, &syntaxErr) {
+				pos = syntaxErr.Pos
+			}
+
+			doc.report(pos, err)
+			break
+		}
+
+		positions := parser.Positions()
+		pos := positions[len(positions)-1]
+		switch node := stmt.(type) {
+		case ast.Def:
+			doc.definitions[node.Name] = pos
+			doc.docs[node.Name] = node.Doc
+		case ast.FDef:
+			doc.definitions[node.Name] = pos
+			doc.docs[node.Name] = node.Doc
+		}
+
+		if _, err = interpreter.Evaluate(stmt); err != nil {
+			doc.report(pos, err)
+		}
+	}
+
+	doc.names = interpreter.Names()
+
+	return doc
+}
+
+// scanIdents returns identifiers up to the first lexing error
+func scanIdents(text string) (idents []ident) {
+	lexer := lex.NewLexer(text)
+	for {
+		lexeme, err := lexer.Next()
+		if err != nil || lexeme.Type == lex.EOF {
+			return idents
+		}
+
+		if lexeme.Type == lex.Id {
+			idents = append(idents, ident{name: lexeme.Value, pos: lexer.Position()})
+		}
+	}
+}
+
+// report adds a diagnostic spanning from the position to the end of its line
+func (d *document) report(pos lex.Position, err error) {
+	end := pos
+	if pos.Line < len(d.lines) {
+		end.Char = utf8.RuneCountInString(strings.TrimRight(d.lines[pos.Line], "\r"))
+	}
+
+	if end.Char < pos.Char {
+		end.Char = pos.Char
+	}
+
+	d.diagnostics = append(d.diagnostics, diagnostic{
+		Range:    textRange{Start: d.toProtocol(pos), End: d.toProtocol(end)},
+		Severity: severityError,
+		Source:   "calculator",
+		Message:  err.Error(),
+	})
+}
+
+// identAt returns the identifier under the position sent by the client
+func (d *document) identAt(pos position) (ident, bool) {
+	for _, id := range d.idents {
+		span := d.identRange(id)
+		if pos.Line == span.Start.Line && pos.Character >= span.Start.Character && pos.Character <= span.End.Character {
+			return id, true
+		}
+	}
+
+	return ident{}, false
+}
+
+func (d *document) identRange(id ident) textRange {
+	return textRange{
+		Start: d.toProtocol(id.pos),
+		End:   d.toProtocol(lex.Position{Line: id.pos.Line, Char: id.pos.Char + utf8.RuneCountInString(id.name)}),
+	}
+}
+
+// toProtocol converts the position, counting runes in the line, into the one
+// counting UTF-16 code units, as the protocol requires
+func (d *document) toProtocol(pos lex.Position) position {
+	if pos.Line >= len(d.lines) {
+		return position{Line: pos.Line, Character: pos.Char}
+	}
+
+	units, runes := 0, 0
+	for _, r := range d.lines[pos.Line] {
+		if runes == pos.Char {
+			break
+		}
+
+		units += utf16Len(r)
+		runes++
+	}
+
+	return position{Line: pos.Line, Character: units}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+
+	return 1
+}
@@ -0,0 +1,284 @@
+package lsp
+
+import (
+	"bufio"
+	"calculator/backend/interpret"
+	"calculator/frontend/parse/ast"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Server is a language server for calculator scripts, speaking the Language
+// Server Protocol over a stream. Documents are evaluated on every change, so
+// errors are reported as diagnostics, while hovers show values of the names
+type Server struct {
+	newInterpreter func() *interpret.Interpreter
+	out            io.Writer
+	documents      map[string]*document
+	shutdown       bool
+}
+
+// New returns a server, evaluating every document by a fresh interpreter
+func New(newInterpreter func() *interpret.Interpreter) *Server {
+	return &Server{
+		newInterpreter: newInterpreter,
+		documents:      make(map[string]*document),
+	}
+}
+
+// Serve handles messages until the exit notification is received or the input
+// is over
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	reader := bufio.NewReader(in)
+
+	for {
+		body, err := readMessage(reader)
+		switch {
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		var msg message
+		if err = json.Unmarshal(body, &msg); err != nil {
+			if err = s.replyError(nil, &rpcError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+
+			return nil
+		}
+
+		if err = s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches the message and replies to requests. Errors of requests are
+// sent back to the client, so only failures of writing are returned
+func (s *Server) handle(msg message) error {
+	result, err := s.dispatch(msg)
+	var rpcErr *rpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		if msg.ID == nil {
+			return nil
+		}
+
+		return s.replyError(msg.ID, rpcErr)
+	case err != nil:
+		return err
+	case msg.ID == nil:
+		return nil
+	}
+
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *Server) dispatch(msg message) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		var result initializeResult
+		result.Capabilities.TextDocumentSync = syncFull
+		result.Capabilities.HoverProvider = true
+		result.Capabilities.DefinitionProvider = true
+		result.ServerInfo.Name = "calculator"
+
+		return result, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		// the full text is synced, so only the last change matters
+		if len(params.ContentChanges) > 0 {
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			return nil, s.update(params.TextDocument.URI, text)
+		}
+
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := decode(msg.Params, &params); err != nil {
+			return nil, err
+		}
+
+		delete(s.documents, params.TextDocument.URI)
+
+		return nil, s.publish(params.TextDocument.URI, nil)
+	case "textDocument/hover":
+		return s.positional(msg, s.hover)
+	case "textDocument/definition":
+		return s.positional(msg, s.definition)
+	case "textDocument/completion":
+		return s.positional(msg, s.completion)
+	}
+
+	if msg.ID == nil {
+		// unknown notifications, like initialized, are ignored
+		return nil, nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+// positional decodes parameters of a request made at a position in a document
+// and passes them to the handler. Requests to unknown documents result in null
+func (s *Server) positional(
+	msg message, handler func(doc *document, uri string, pos position) any,
+) (any, error) {
+	var params textDocumentPositionParams
+	if err := decode(msg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	doc, found := s.documents[params.TextDocument.URI]
+	if !found {
+		return nil, nil
+	}
+
+	return handler(doc, params.TextDocument.URI, params.Position), nil
+}
+
+func (s *Server) update(uri, text string) error {
+	doc := analyze(text, s.newInterpreter())
+	s.documents[uri] = doc
+
+	return s.publish(uri, doc.diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []diagnostic{}
+	}
+
+	return writeMessage(s.out, struct {
+		JSONRPC string                   `json:"jsonrpc"`
+		Method  string                   `json:"method"`
+		Params  publishDiagnosticsParams `json:"params"`
+	}{"2.0", "textDocument/publishDiagnostics", publishDiagnosticsParams{uri, diagnostics}})
+}
+
+func (s *Server) hover(doc *document, _ string, pos position) any {
+	id, found := doc.identAt(pos)
+	if !found {
+		return nil
+	}
+
+	value, found := doc.names[id.name]
+	if !found {
+		return nil
+	}
+
+	contents := "```\n" + describe(id.name, value) + "\n```"
+	if comment := doc.docs[id.name]; len(comment) > 0 {
+		contents += "\n\n" + comment
+	}
+
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: contents},
+		Range:    doc.identRange(id),
+	}
+}
+
+func (s *Server) definition(doc *document, uri string, pos position) any {
+	id, found := doc.identAt(pos)
+	if !found {
+		return nil
+	}
+
+	defined, found := doc.definitions[id.name]
+	if !found {
+		return nil
+	}
+
+	return location{URI: uri, Range: doc.identRange(ident{name: id.name, pos: defined})}
+}
+
+func (s *Server) completion(doc *document, _ string, _ position) any {
+	names := make([]string, 0, len(doc.names))
+	for name := range doc.names {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	items := make([]completionItem, len(names))
+	for i, name := range names {
+		items[i] = completionItem{Label: name, Kind: completionVariable}
+		if isFunction(doc.names[name]) {
+			items[i].Kind = completionFunction
+		}
+
+		if _, defined := doc.definitions[name]; !defined {
+			items[i].Detail = "builtin"
+		}
+	}
+
+	return items
+}
+
+func (s *Server) replyError(id *json.RawMessage, err *rpcError) error {
+	return writeMessage(s.out, errorResponse{JSONRPC: "2.0", ID: id, Error: err})
+}
+
+// describe returns the signature of a function or the value of a variable
+func describe(name string, value ast.Node) string {
+	switch v := value.(type) {
+	case ast.Closure:
+		clauses := make([]string, len(v.Clauses))
+		for i, clause := range v.Clauses {
+			clauses[i] = ast.Format(clause)
+		}
+
+		return strings.Join(clauses, "\n")
+	case ast.NamedFunction:
+		return name + "(" + strings.Join(v.Names, ", ") + ") (builtin)"
+	case ast.Function, ast.Form, ast.Impure:
+		return name + "(...) (builtin)"
+	}
+
+	return fmt.Sprintf("%s = %v", name, value)
+}
+
+func isFunction(node ast.Node) bool {
+	switch node.(type) {
+	case ast.Function, ast.Closure, ast.Form, ast.NamedFunction, ast.Impure:
+		return true
+	}
+
+	return false
+}
+
+func decode(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+
+	return nil
+}
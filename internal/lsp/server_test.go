@@ -0,0 +1,119 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"calculator/backend/interpret"
+	"calculator/frontend/parse/ast"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	const uri = "file:///formulas.calc"
+
+	var in bytes.Buffer
+	send := func(id int, method string, params any) {
+		msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+		if id > 0 {
+			msg["id"] = id
+		}
+
+		require.NoError(t, writeMessage(&in, msg))
+	}
+
+	at := func(line, char int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": char},
+		}
+	}
+
+	send(1, "initialize", map[string]any{})
+	send(0, "initialized", map[string]any{})
+	send(0, "textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "text": "a -> 2\n/* scales by a */ f(x) -> x*a\nf(b)\n"},
+	})
+	send(2, "textDocument/hover", at(2, 0))
+	send(3, "textDocument/definition", at(1, 28))
+	send(4, "textDocument/completion", at(2, 0))
+	send(0, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri},
+		"contentChanges": []any{map[string]any{"text": "a -> (1"}},
+	})
+	send(5, "unknown", map[string]any{})
+	send(6, "shutdown", nil)
+	send(0, "exit", nil)
+
+	srv := New(func() *interpret.Interpreter {
+		return interpret.NewInterpreter(map[string]ast.Node{"pi": ast.Float(3.14)})
+	})
+	var out bytes.Buffer
+	require.NoError(t, srv.Serve(&in, &out))
+
+	var messages []map[string]any
+	reader := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+		var msg map[string]any
+		require.NoError(t, json.Unmarshal(body, &msg))
+		messages = append(messages, msg)
+	}
+
+	require.Len(t, messages, 8)
+	require.Equal(t, true, messages[0]["result"].(map[string]any)["capabilities"].(map[string]any)["hoverProvider"])
+
+	require.Equal(t, map[string]any{
+		"uri": uri,
+		"diagnostics": []any{map[string]any{
+			"range": map[string]any{
+				"start": map[string]any{"line": float64(2), "character": float64(0)},
+				"end":   map[string]any{"line": float64(2), "character": float64(4)},
+			},
+			"severity": float64(1),
+			"source":   "calculator",
+			"message":  "name not found: b",
+		}},
+	}, messages[1]["params"])
+
+	require.Equal(t, "```\nf(x) -> x * a\n```\n\nscales by a", messages[2]["result"].(map[string]any)["contents"].(map[string]any)["value"])
+
+	require.Equal(t, map[string]any{
+		"uri": uri,
+		"range": map[string]any{
+			"start": map[string]any{"line": float64(0), "character": float64(0)},
+			"end":   map[string]any{"line": float64(0), "character": float64(1)},
+		},
+	}, messages[3]["result"])
+
+	require.Equal(t, []any{
+		map[string]any{"label": "a", "kind": float64(completionVariable)},
+		map[string]any{"label": "f", "kind": float64(completionFunction)},
+		map[string]any{"label": "pi", "kind": float64(completionVariable), "detail": "builtin"},
+	}, messages[4]["result"])
+
+	diagnostics := messages[5]["params"].(map[string]any)["diagnostics"].([]any)
+	require.Len(t, diagnostics, 1)
+
+	require.Equal(t, float64(codeMethodNotFound), messages[6]["error"].(map[string]any)["code"])
+	require.Contains(t, messages[7], "result")
+}
+
+func TestSyntaxError(t *testing.T) {
+	doc := analyze("a -> 5\nb -> a +\n", interpret.NewInterpreter(nil))
+	require.Len(t, doc.diagnostics, 1)
+
+	srv := New(nil)
+	require.NotNil(t, srv.hover(doc, "", position{Line: 0, Character: 0}))
+	require.NotNil(t, srv.definition(doc, "", position{Line: 1, Character: 5}))
+	require.Contains(t, doc.names, "a")
+}
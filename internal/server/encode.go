@@ -0,0 +1,92 @@
+package server
+
+import (
+	"calculator/frontend/parse/ast"
+	"fmt"
+)
+
+// encodeNode converts the tree into JSON-friendly maps, tagging every node
+// with its type
+func encodeNode(node ast.Node) any {
+	switch n := node.(type) {
+	case ast.Integer:
+		return map[string]any{"type": "Integer", "value": n}
+	case ast.Float:
+		return map[string]any{"type": "Float", "value": n}
+	case ast.Literal:
+		return map[string]any{"type": "Literal", "value": encodeNode(n.Value), "radix": n.Radix, "text": n.Text}
+	case ast.ID:
+		return map[string]any{"type": "ID", "name": n}
+	case ast.UnOp:
+		return map[string]any{"type": "UnOp", "op": n.Op, "value": encodeNode(n.Value)}
+	case ast.BinOp:
+		return map[string]any{
+			"type":  "BinOp",
+			"op":    n.Op,
+			"left":  encodeNode(n.Left),
+			"right": encodeNode(n.Right),
+		}
+	case ast.FCall:
+		return map[string]any{"type": "FCall", "target": encodeNode(n.Target), "args": encodeNodes(n.Args)}
+	case ast.FDef:
+		encoded := map[string]any{
+			"type":     "FDef",
+			"name":     n.Name,
+			"args":     append([]string{}, n.Args...),
+			"body":     encodeNode(n.Body),
+			"variadic": n.Variadic,
+			"memo":     n.Memo,
+		}
+
+		defaults := make(map[string]any)
+		for i, value := range n.Defaults {
+			if value != nil {
+				defaults[n.Args[i]] = encodeNode(value)
+			}
+		}
+
+		if len(defaults) > 0 {
+			encoded["defaults"] = defaults
+		}
+
+		if n.Patterns != nil {
+			patterns := make([]any, len(n.Patterns))
+			for i, pattern := range n.Patterns {
+				if pattern != nil {
+					patterns[i] = encodeNode(pattern)
+				}
+			}
+
+			encoded["patterns"] = patterns
+		}
+
+		if n.Guard != nil {
+			encoded["guard"] = encodeNode(n.Guard)
+		}
+
+		return encoded
+	case ast.Def:
+		return map[string]any{"type": "Def", "name": n.Name, "value": encodeNode(n.Value), "const": n.Const}
+	case ast.Assign:
+		return map[string]any{"type": "Assign", "name": n.Name, "value": encodeNode(n.Value)}
+	case ast.Equation:
+		return map[string]any{"type": "Equation", "left": encodeNode(n.Left), "right": encodeNode(n.Right)}
+	case ast.NamedArg:
+		return map[string]any{"type": "NamedArg", "name": n.Name, "value": encodeNode(n.Value)}
+	case ast.Spread:
+		return map[string]any{"type": "Spread", "value": encodeNode(n.Value)}
+	case ast.Block:
+		return map[string]any{"type": "Block", "body": encodeNodes(n.Body)}
+	}
+
+	return map[string]any{"type": fmt.Sprintf("%T", node), "value": fmt.Sprint(node)}
+}
+
+func encodeNodes(nodes []ast.Node) []any {
+	encoded := make([]any, len(nodes))
+	for i, node := range nodes {
+		encoded[i] = encodeNode(node)
+	}
+
+	return encoded
+}
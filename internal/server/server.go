@@ -0,0 +1,408 @@
+package server
+
+import (
+	"calculator/backend/interpret"
+	"calculator/frontend/lex"
+	"calculator/frontend/parse"
+	"calculator/frontend/parse/ast"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBodySize limits the size of request bodies
+const maxBodySize = 1 << 20
+
+// minSweepInterval bounds how often sessions are checked for expiration, so tiny
+// idle timeouts don't keep the server busy
+const minSweepInterval = time.Second
+
+// Server evaluates expressions over HTTP. Stateless endpoints use a fresh
+// interpreter for every request, while sessions keep their own interpreters
+// until they are deleted or stay idle for too long:
+//
+//	POST   /eval                {"expr": "2+2"} -> {"result": "4", "number": 4}
+//	POST   /parse               {"expr": "2+2"} -> {"ast": [...]}
+//	POST   /sessions            -> {"id": "..."}
+//	POST   /sessions/{id}/eval  {"expr": "x -> 5"} -> {"result": "5", "number": 5}
+//	DELETE /sessions/{id}
+//
+// Failures are reported as {"error": {"kind": "...", "message": "..."}}. Evaluations
+// exceeding limits of the interpreter are reported by the limit kind. Limits
+// apply to all the statements of a request together
+type Server struct {
+	newInterpreter func() *interpret.Interpreter
+	idleTimeout    time.Duration
+	maxSessions    int
+	mux            *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	stop     chan struct{}
+	once     sync.Once
+}
+
+type session struct {
+	mu          sync.Mutex
+	interpreter *interpret.Interpreter
+	lastUsed    time.Time
+}
+
+// New returns a server, creating interpreters by the function. Sessions staying
+// idle longer than the timeout are removed, unless it is zero. No more than
+// maxSessions sessions may exist at once, unless it is zero
+func New(newInterpreter func() *interpret.Interpreter, idleTimeout time.Duration, maxSessions int) *Server {
+	s := &Server{
+		newInterpreter: newInterpreter,
+		idleTimeout:    idleTimeout,
+		maxSessions:    maxSessions,
+		mux:            http.NewServeMux(),
+		sessions:       make(map[string]*session),
+		stop:           make(chan struct{}),
+	}
+
+	s.mux.HandleFunc("/eval", s.handleEval)
+	s.mux.HandleFunc("/parse", s.handleParse)
+	s.mux.HandleFunc("/sessions", s.handleSessions)
+	s.mux.HandleFunc("/sessions/", s.handleSession)
+
+	if idleTimeout > 0 {
+		go s.expire()
+	}
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close stops expiring sessions
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+type request struct {
+	Expr string `json:"expr"`
+}
+
+type evalResponse struct {
+	Result string      `json:"result"`
+	Number json.Number `json:"number,omitempty"`
+}
+
+type parseResponse struct {
+	AST []any `json:"ast"`
+}
+
+type sessionResponse struct {
+	ID string `json:"id"`
+}
+
+// Kinds of errors
+const (
+	KindRequest = "request"
+	KindParse   = "parse"
+	KindEval    = "eval"
+	KindLimit   = "limit"
+	KindSession = "session"
+)
+
+type apiError struct {
+	status  int
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (s *Server) handleEval(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	expr, apiErr := readRequest(w, r)
+	if apiErr == nil {
+		var resp evalResponse
+		if resp, apiErr = evaluate(r.Context(), s.newInterpreter(), expr); apiErr == nil {
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+	}
+
+	writeError(w, apiErr)
+}
+
+func (s *Server) handleParse(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	expr, apiErr := readRequest(w, r)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	tree, err := parse.NewParser(lex.NewLexer(expr)).Parse()
+	if err != nil {
+		writeError(w, &apiError{status: http.StatusUnprocessableEntity, Kind: KindParse, Message: err.Error()})
+		return
+	}
+
+	resp := parseResponse{AST: make([]any, len(tree))}
+	for i, node := range tree {
+		resp.AST[i] = encodeNode(node)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		writeError(w, &apiError{status: http.StatusInternalServerError, Kind: KindSession, Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		s.mu.Unlock()
+		writeError(w, &apiError{
+			status:  http.StatusServiceUnavailable,
+			Kind:    KindSession,
+			Message: fmt.Sprintf("too many sessions, at most %d may exist at once", s.maxSessions),
+		})
+
+		return
+	}
+
+	s.sessions[id] = &session{
+		interpreter: s.newInterpreter(),
+		lastUsed:    time.Now(),
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, sessionResponse{ID: id})
+}
+
+// handleSession serves /sessions/{id} and /sessions/{id}/eval
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/")
+
+	switch action {
+	case "":
+		if !allow(w, r, http.MethodDelete) {
+			return
+		}
+
+		s.mu.Lock()
+		_, found := s.sessions[id]
+		delete(s.sessions, id)
+		s.mu.Unlock()
+
+		if !found {
+			writeError(w, errNoSession(id))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	case "eval":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+
+		s.mu.Lock()
+		sess, found := s.sessions[id]
+		s.mu.Unlock()
+
+		if !found {
+			writeError(w, errNoSession(id))
+			return
+		}
+
+		expr, apiErr := readRequest(w, r)
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+
+		sess.mu.Lock()
+		resp, apiErr := evaluate(r.Context(), sess.interpreter, expr)
+		sess.lastUsed = time.Now()
+		sess.mu.Unlock()
+
+		if apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// expire periodically removes sessions staying idle for longer than the timeout
+func (s *Server) expire() {
+	interval := s.idleTimeout / 2
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.sweep(now)
+		}
+	}
+}
+
+// sweep removes sessions staying idle for longer than the timeout. Sessions are
+// checked apart from the server lock, so an evaluation running in one of them
+// doesn't hold up requests to the others
+func (s *Server) sweep(now time.Time) {
+	s.mu.Lock()
+	sessions := make(map[string]*session, len(s.sessions))
+	for id, sess := range s.sessions {
+		sessions[id] = sess
+	}
+	s.mu.Unlock()
+
+	for id, sess := range sessions {
+		sess.mu.Lock()
+		idle := now.Sub(sess.lastUsed)
+		sess.mu.Unlock()
+
+		if idle <= s.idleTimeout {
+			continue
+		}
+
+		s.mu.Lock()
+		if s.sessions[id] == sess {
+			delete(s.sessions, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func evaluate(ctx context.Context, interpreter *interpret.Interpreter, expr string) (evalResponse, *apiError) {
+	tree, err := parse.NewParser(lex.NewLexer(expr)).Parse()
+	if err != nil {
+		return evalResponse{}, &apiError{status: http.StatusUnprocessableEntity, Kind: KindParse, Message: err.Error()}
+	}
+
+	defer interpreter.Begin(ctx)()
+
+	var result ast.Node
+	for _, branch := range tree {
+		if result, err = interpreter.EvaluateContext(ctx, branch); err != nil {
+			kind := KindEval
+			if isLimit(err) {
+				kind = KindLimit
+			}
+
+			return evalResponse{}, &apiError{status: http.StatusUnprocessableEntity, Kind: kind, Message: err.Error()}
+		}
+	}
+
+	resp := evalResponse{Result: fmt.Sprint(result)}
+	switch value := result.(type) {
+	case ast.Integer:
+		resp.Number = json.Number(strconv.FormatInt(value, 10))
+	case ast.Float:
+		// JSON has no representation of NaN and infinities
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return evalResponse{}, &apiError{
+				status:  http.StatusUnprocessableEntity,
+				Kind:    KindEval,
+				Message: fmt.Sprintf("result is not a finite number: %v", value),
+			}
+		}
+
+		resp.Number = json.Number(strconv.FormatFloat(value, 'g', -1, 64))
+	}
+
+	return resp, nil
+}
+
+// isLimit reports whether the evaluation was aborted by exceeding the limits
+func isLimit(err error) bool {
+	for _, limitErr := range []error{
+		interpret.ErrDepthLimit, interpret.ErrStepLimit, interpret.ErrTimeLimit, interpret.ErrNumberLimit,
+	} {
+		if errors.Is(err, limitErr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request) (string, *apiError) {
+	var req request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := decoder.Decode(&req); err != nil {
+		return "", &apiError{status: http.StatusBadRequest, Kind: KindRequest, Message: "malformed request: " + err.Error()}
+	}
+
+	return req.Expr, nil
+}
+
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeError(w, &apiError{
+		status:  http.StatusMethodNotAllowed,
+		Kind:    KindRequest,
+		Message: "method not allowed: " + r.Method,
+	})
+
+	return false
+}
+
+func errNoSession(id string) *apiError {
+	return &apiError{status: http.StatusNotFound, Kind: KindSession, Message: "session not found: " + id}
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	writeJSON(w, err.status, struct {
+		Error *apiError `json:"error"`
+	}{err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.New("cannot generate session id: " + err.Error())
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"calculator/backend/interpret"
+	"calculator/frontend/parse/ast"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	srv := New(func() *interpret.Interpreter {
+		return interpret.NewInterpreter(map[string]ast.Node{"x": ast.Integer(5)})
+	}, time.Minute, 0)
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	do := func(t *testing.T, method, path, body string) (int, map[string]any) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded map[string]any
+		if resp.StatusCode != http.StatusNoContent {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		}
+
+		return resp.StatusCode, decoded
+	}
+
+	t.Run("eval", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/eval", `{"expr": "y -> 2\nx*y"}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, map[string]any{"result": "10", "number": float64(10)}, body)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/eval", `{"expr": "y"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Equal(t, map[string]any{"kind": KindEval, "message": "name not found: y"}, body["error"])
+
+		status, body = do(t, http.MethodPost, "/eval", `{"expr": "1+"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Equal(t, KindParse, body["error"].(map[string]any)["kind"])
+
+		status, body = do(t, http.MethodPost, "/eval", `{"expr":`)
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Equal(t, KindRequest, body["error"].(map[string]any)["kind"])
+
+		status, _ = do(t, http.MethodGet, "/eval", "")
+		require.Equal(t, http.StatusMethodNotAllowed, status)
+
+		for expr, want := range map[string]string{
+			"0.0/0":       "result is not a finite number: NaN",
+			"-1e308 * 10": "result is not a finite number: -Inf",
+		} {
+			status, body = do(t, http.MethodPost, "/eval", `{"expr": "`+expr+`"}`)
+			require.Equal(t, http.StatusUnprocessableEntity, status, expr)
+			require.Equal(t, map[string]any{"kind": KindEval, "message": want}, body["error"], expr)
+		}
+
+		status, body = do(t, http.MethodPost, "/eval", `{"expr": "f(n) -> f(n)\nf(1)"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Equal(t, map[string]any{"kind": KindLimit, "message": "recursion depth limit exceeded"}, body["error"])
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/parse", `{"expr": "-a"}`)
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, []any{map[string]any{
+			"type":  "UnOp",
+			"op":    "UN_MINUS",
+			"value": map[string]any{"type": "ID", "name": "a"},
+		}}, body["ast"])
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/sessions", "")
+		require.Equal(t, http.StatusCreated, status)
+		id := body["id"].(string)
+
+		status, _ = do(t, http.MethodPost, "/sessions/"+id+"/eval", `{"expr": "y -> 3"}`)
+		require.Equal(t, http.StatusOK, status)
+		_, body = do(t, http.MethodPost, "/sessions/"+id+"/eval", `{"expr": "x*y"}`)
+		require.Equal(t, "15", body["result"])
+
+		status, _ = do(t, http.MethodDelete, "/sessions/"+id, "")
+		require.Equal(t, http.StatusNoContent, status)
+		status, body = do(t, http.MethodPost, "/sessions/"+id+"/eval", `{"expr": "y"}`)
+		require.Equal(t, http.StatusNotFound, status)
+		require.Equal(t, KindSession, body["error"].(map[string]any)["kind"])
+	})
+
+	t.Run("tiny idle timeout", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			New(func() *interpret.Interpreter { return nil }, time.Nanosecond, 0).Close()
+		})
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		_, body := do(t, http.MethodPost, "/sessions", "")
+		id := body["id"].(string)
+
+		srv.sweep(time.Now())
+		status, _ := do(t, http.MethodPost, "/sessions/"+id+"/eval", `{"expr": "1"}`)
+		require.Equal(t, http.StatusOK, status)
+
+		srv.sweep(time.Now().Add(2 * time.Minute))
+		status, _ = do(t, http.MethodPost, "/sessions/"+id+"/eval", `{"expr": "1"}`)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("busy session", func(t *testing.T) {
+		_, body := do(t, http.MethodPost, "/sessions", "")
+		srv.mu.Lock()
+		busy := srv.sessions[body["id"].(string)]
+		srv.mu.Unlock()
+
+		busy.mu.Lock()
+		swept := make(chan struct{})
+		go func() {
+			srv.sweep(time.Now())
+			close(swept)
+		}()
+
+		status, _ := do(t, http.MethodPost, "/sessions", "")
+		require.Equal(t, http.StatusCreated, status)
+
+		busy.mu.Unlock()
+		<-swept
+	})
+}
+
+func TestLimits(t *testing.T) {
+	srv := New(func() *interpret.Interpreter {
+		interpreter := interpret.NewInterpreter(nil)
+		interpreter.SetLimits(interpret.Limits{MaxSteps: 100})
+
+		return interpreter
+	}, 0, 2)
+	defer srv.Close()
+
+	post := func(t *testing.T, path, body string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+		var decoded map[string]any
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&decoded))
+
+		return rec.Code, decoded
+	}
+
+	t.Run("statements share limits", func(t *testing.T) {
+		program := "down(0) -> 0; down(n) -> down(n - 1); down(10)"
+		status, _ := post(t, "/eval", `{"expr": "`+program+`"}`)
+		require.Equal(t, http.StatusOK, status)
+
+		status, body := post(t, "/eval", `{"expr": "`+program+`; down(10)"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, status)
+		require.Equal(t, map[string]any{"kind": KindLimit, "message": "evaluation step limit exceeded"}, body["error"])
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		for range [2]struct{}{} {
+			status, _ := post(t, "/sessions", "")
+			require.Equal(t, http.StatusCreated, status)
+		}
+
+		status, body := post(t, "/sessions", "")
+		require.Equal(t, http.StatusServiceUnavailable, status)
+		require.Equal(t, map[string]any{
+			"kind":    KindSession,
+			"message": "too many sessions, at most 2 may exist at once",
+		}, body["error"])
+	})
+}
@@ -0,0 +1,137 @@
+package calculator
+
+import (
+	"calculator/backend/interpret"
+	"calculator/frontend/parse/ast"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrUndefined is returned when a name isn't bound
+var ErrUndefined = errors.New("undefined name")
+
+// Errors of exceeded limits, wrapped by EvalError
+var (
+	ErrDepthLimit  = interpret.ErrDepthLimit
+	ErrStepLimit   = interpret.ErrStepLimit
+	ErrTimeLimit   = interpret.ErrTimeLimit
+	ErrNumberLimit = interpret.ErrNumberLimit
+)
+
+// Limits bound resources a single evaluation may take. Zero means no limit. By
+// default, only the recursion depth is limited
+type Limits = interpret.Limits
+
+// SyntaxError is returned when the source can't be parsed. Line and Column are
+// counted from one
+type SyntaxError struct {
+	Line, Column int
+	Err          error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// EvalError is returned when the evaluation fails, like on division by zero
+type EvalError struct {
+	Err error
+}
+
+func (e *EvalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// TypeError is returned when a value can't be converted from or into a Go type
+type TypeError struct {
+	Value  any
+	Wanted string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("cannot use %v (%T) as %s", e.Value, e.Value, e.Wanted)
+}
+
+// Value is a value of the language: a number, a list, a function or a symbolic
+// expression
+type Value struct {
+	node ast.Node
+}
+
+// ValueOf converts Go numbers into numbers, slices into lists and functions
+// into builtins, wrapping them by Wrap unless they are of the ast.Function type.
+// Values and nodes of the language are taken as is
+func ValueOf(value any) (Value, error) {
+	switch v := value.(type) {
+	case Value:
+		return v, nil
+	case ast.Integer, ast.Float, ast.List, ast.Function, ast.Closure, ast.Form, ast.NamedFunction, ast.Impure:
+		return Value{v}, nil
+	}
+
+	return fromGo(reflect.ValueOf(value))
+}
+
+// Node returns the value as the interpreter represents it
+func (v Value) Node() ast.Node {
+	return v.node
+}
+
+// Int returns the value if it is an integer
+func (v Value) Int() (int64, error) {
+	if i, ok := v.node.(ast.Integer); ok {
+		return i, nil
+	}
+
+	return 0, &TypeError{Value: v.node, Wanted: "integer"}
+}
+
+// Float returns the value of a number, converting integers
+func (v Value) Float() (float64, error) {
+	switch n := v.node.(type) {
+	case ast.Float:
+		return n, nil
+	case ast.Integer:
+		return float64(n), nil
+	}
+
+	return 0, &TypeError{Value: v.node, Wanted: "float"}
+}
+
+// List returns elements of the value if it is a list
+func (v Value) List() ([]Value, error) {
+	list, ok := v.node.(ast.List)
+	if !ok {
+		return nil, &TypeError{Value: v.node, Wanted: "list"}
+	}
+
+	values := make([]Value, len(list))
+	for i, elem := range list {
+		values[i] = Value{elem}
+	}
+
+	return values, nil
+}
+
+// IsFunction reports whether the value can be called
+func (v Value) IsFunction() bool {
+	switch v.node.(type) {
+	case ast.Function, ast.Closure, ast.Form, ast.NamedFunction, ast.Impure:
+		return true
+	}
+
+	return false
+}
+
+func (v Value) String() string {
+	return fmt.Sprint(v.node)
+}